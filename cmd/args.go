@@ -17,27 +17,64 @@ func FilterArgs(inputArgs []string) ([]string, []string) {
 		NewDebugCmd(),
 		NewResetCmd(),
 		NewClearBackupCmd(),
+		NewCheckCmd(),
+		NewTestCmd(),
+		NewLspCmd(),
+		NewQueryCmd(),
+		NewLintCmd(),
 	}, terraformCommands...) {
 		subCommands[cmd.Use] = struct{}{}
 	}
 	mptfVarFlags := map[string]struct{}{
-		"--tf-dir":        {},
-		"--mptf-dir":      {},
-		"--mptf-var":      {},
-		"--mptf-var-file": {},
-		"--help":          {},
-		"--version":       {},
+		"--tf-dir":          {},
+		"--mptf-dir":        {},
+		"--mptf-var":        {},
+		"--mptf-var-file":   {},
+		"--backup-strategy": {},
+		"--parallelism":     {},
+		"--conflict-mode":   {},
+		"--help":            {},
+		"--version":         {},
+	}
+	// Flags defined by a single subcommand only, a wrapped terraform command
+	// keeps flags of the same name as its own.
+	subCommandVarFlags := map[string]map[string]struct{}{
+		"transform": {"--report-json": {}},
+		"reset":     {"--transform": {}},
+		"debug":     {"--eval": {}, "--eval-file": {}},
+		"query":     {"--expression": {}, "--format": {}},
+		"lint":      {"--format": {}},
+	}
+	subCommand := ""
+	isMptfVarFlag := func(arg string) bool {
+		if _, ok := mptfVarFlags[arg]; ok {
+			return true
+		}
+		_, ok := subCommandVarFlags[subCommand][arg]
+		return ok
 	}
 	mptfShortHands := map[string]struct{}{
 		"-r": {},
 		"-h": {},
 		"-v": {},
 	}
+	// Boolean flags never take the next argument as their value.
+	mptfBoolFlags := map[string]struct{}{
+		"--recursive":            {},
+		"--keep-adjacent-blocks": {},
+		"--plan":                 {},
+		"--opentofu":             {},
+		"--include-overrides":    {},
+		"--verify-idempotent":    {},
+	}
 	for i := 0; i < len(inputArgs); i++ {
 		arg := inputArgs[i]
 		if _, isSubCommand := subCommands[arg]; isSubCommand {
+			if subCommand == "" {
+				subCommand = arg
+			}
 			mptfArgs = append(mptfArgs, arg)
-		} else if _, isMptfVarFlag := mptfVarFlags[arg]; isMptfVarFlag {
+		} else if isMptfVarFlag(arg) {
 			mptfArgs = append(mptfArgs, arg)
 			if i != len(inputArgs)-1 && !strings.HasPrefix(inputArgs[i+1], "-") {
 				mptfArgs = append(mptfArgs, inputArgs[i+1])
@@ -45,6 +82,8 @@ func FilterArgs(inputArgs []string) ([]string, []string) {
 			}
 		} else if _, isMptfShorthand := mptfShortHands[arg]; isMptfShorthand {
 			mptfArgs = append(mptfArgs, arg)
+		} else if _, isMptfBoolFlag := mptfBoolFlags[arg]; isMptfBoolFlag {
+			mptfArgs = append(mptfArgs, arg)
 		} else {
 			nonMptfArgs = append(nonMptfArgs, arg)
 		}
@@ -32,6 +32,60 @@ func TestFilterArgs(t *testing.T) {
 			expectedMptf:    []string{"mapotf", "apply", "--mptf-var", "mptfa=b", "--mptf-var-file", "mptf.var"},
 			expectedNonMptf: []string{"-var", "a=b", "-var-file=\"terraform.tfvars\"", "-var", "c=d"},
 		},
+		{
+			name:            "Test with boolean mapotf flags before a terraform argument",
+			inputArgs:       []string{"mapotf", "transform", "--plan", "--keep-adjacent-blocks", "--recursive", "-var", "a=b"},
+			expectedMptf:    []string{"mapotf", "transform", "--plan", "--keep-adjacent-blocks", "--recursive"},
+			expectedNonMptf: []string{"-var", "a=b"},
+		},
+		{
+			name:            "Test with verify idempotent flag",
+			inputArgs:       []string{"mapotf", "transform", "--verify-idempotent", "-var", "a=b"},
+			expectedMptf:    []string{"mapotf", "transform", "--verify-idempotent"},
+			expectedNonMptf: []string{"-var", "a=b"},
+		},
+		{
+			name:            "Test with report json path",
+			inputArgs:       []string{"mapotf", "transform", "--report-json", "report.json", "-var", "a=b"},
+			expectedMptf:    []string{"mapotf", "transform", "--report-json", "report.json"},
+			expectedNonMptf: []string{"-var", "a=b"},
+		},
+		{
+			name:            "Test with opentofu flag",
+			inputArgs:       []string{"mapotf", "plan", "--opentofu", "-var", "a=b"},
+			expectedMptf:    []string{"mapotf", "plan", "--opentofu"},
+			expectedNonMptf: []string{"-var", "a=b"},
+		},
+		{
+			name:            "Test with debug eval expressions",
+			inputArgs:       []string{"mapotf", "debug", "--mptf-dir", "/testMptf", "--eval", "keys(data.resource.all.result)", "--eval-file", "queries.hcl"},
+			expectedMptf:    []string{"mapotf", "debug", "--mptf-dir", "/testMptf", "--eval", "keys(data.resource.all.result)", "--eval-file", "queries.hcl"},
+			expectedNonMptf: nil,
+		},
+		{
+			name:            "Test with include overrides flag",
+			inputArgs:       []string{"mapotf", "transform", "--include-overrides", "-var", "a=b"},
+			expectedMptf:    []string{"mapotf", "transform", "--include-overrides"},
+			expectedNonMptf: []string{"-var", "a=b"},
+		},
+		{
+			name:            "Test with query flags",
+			inputArgs:       []string{"mapotf", "query", "--expression", "data.resource.all.result", "--format", "json"},
+			expectedMptf:    []string{"mapotf", "query", "--expression", "data.resource.all.result", "--format", "json"},
+			expectedNonMptf: nil,
+		},
+		{
+			name:            "Test with reset transform flag",
+			inputArgs:       []string{"mapotf", "reset", "--transform", "update_in_place.tags"},
+			expectedMptf:    []string{"mapotf", "reset", "--transform", "update_in_place.tags"},
+			expectedNonMptf: nil,
+		},
+		{
+			name:            "Test with subcommand only flags passed to a terraform command",
+			inputArgs:       []string{"mapotf", "plan", "--format", "json", "--eval", "x", "--transform", "y", "--expression", "z", "--tf-dir", "/testTerraform"},
+			expectedMptf:    []string{"mapotf", "plan", "--tf-dir", "/testTerraform"},
+			expectedNonMptf: []string{"--format", "json", "--eval", "x", "--transform", "y", "--expression", "z"},
+		},
 	}
 
 	for _, tt := range tests {
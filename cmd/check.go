@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/spf13/cobra"
+)
+
+func NewCheckCmd() *cobra.Command {
+	recursive := false
+	keepAdjacentBlocks := false
+
+	checkCmd := &cobra.Command{
+		Use:   "check",
+		Short: "Fail when the transforms would change the Terraform code, mapotf check [-r] [--keep-adjacent-blocks] --tf-dir [] --mptf-dir  [path to config files], support mutilple mptf dirs",
+		FParseErrWhitelist: cobra.FParseErrWhitelist{
+			UnknownFlags: true,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			terraform.SetNormalizeOptions(terraform.NormalizeOptions{
+				KeepAdjacentSameKindUnlabeledBlocks: keepAdjacentBlocks,
+			})
+			return check(recursive, cmd.Context(), cmd.OutOrStdout())
+		},
+	}
+
+	checkCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Check all modules or not, default to the root module only.")
+	checkCmd.Flags().BoolVar(&keepAdjacentBlocks, "keep-adjacent-blocks", false, "Use the same whitespace layout as `transform --keep-adjacent-blocks` when deciding whether a file is up to date.")
+	return checkCmd
+}
+
+// check runs the transform pipeline in memory and returns an error listing
+// every file, and every block inside it, that the transforms would change.
+func check(recursive bool, ctx context.Context, out io.Writer) error {
+	changes, applied, err := sandboxedTransform(recursive, ctx, "", false)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(out, "All Terraform files are up to date with the transforms.")
+		return nil
+	}
+	blocks := make(map[string][]string)
+	seen := make(map[string]struct{})
+	for _, a := range applied {
+		for _, b := range a.plan.ChangedBlocks() {
+			path := filepath.Join(a.moduleRef.Dir, b.FileName)
+			key := path + "#" + b.Address
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			blocks[path] = append(blocks[path], b.Address)
+		}
+	}
+	_, _ = fmt.Fprintln(out, "The following files are not up to date with the transforms:")
+	for _, c := range changes {
+		_, _ = fmt.Fprintf(out, "  %s\n", displayPath(c.Path))
+		addresses := blocks[c.Path]
+		if len(addresses) == 0 {
+			_, _ = fmt.Fprintln(out, "    (formatting only)")
+		}
+		for _, address := range addresses {
+			_, _ = fmt.Fprintf(out, "    %s\n", address)
+		}
+	}
+	return fmt.Errorf("%d file(s) would be changed by the transforms, run `mapotf transform` to update them", len(changes))
+}
+
+func init() {
+	rootCmd.AddCommand(NewCheckCmd())
+}
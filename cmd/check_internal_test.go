@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const checkTestMptfConfig = `
+data resource "fake_resource" {
+  resource_type = "fake_resource"
+}
+
+transform update_in_place "fake_resource" {
+ for_each = data.resource.fake_resource.result.fake_resource
+ target_block_address = each.value.mptf.block_address
+ asraw {
+   tags = {}
+ }
+}
+`
+
+func stubCheckEnv(t *testing.T, terraformCode string) afero.Fs {
+	return stubTransformEnv(t, checkTestMptfConfig, terraformCode)
+}
+
+// stubTransformEnv calls the command implementations directly instead of
+// going through rootCmd, whose flag values leak between tests.
+func stubTransformEnv(t *testing.T, mptfConfig, terraformCode string) afero.Fs {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/testData/main.mptf.hcl", []byte(mptfConfig), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/testTerraform/main.tf", []byte(terraformCode), 0644))
+	stub := gostub.Stub(&filesystem.Fs, fs).
+		Stub(&os.Args, []string{"mapotf"}).
+		Stub(&cf, &commonFlags{
+			tfDir:       "/testTerraform",
+			mptfDirs:    []string{"/testData"},
+			parallelism: 1,
+		}).
+		Stub(&pkg.AbsDir, func(dir string) (string, error) {
+			return dir, nil
+		})
+	t.Cleanup(stub.Reset)
+	return fs
+}
+
+func TestCheck_FailsAndListsChangedBlocks(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+
+resource "fake_resource" that {
+  tags = {}
+}
+`
+	fs := stubCheckEnv(t, terraformCode)
+
+	out := new(bytes.Buffer)
+	err := check(false, context.Background(), out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 file(s) would be changed")
+	assert.Equal(t, `The following files are not up to date with the transforms:
+  main.tf
+    resource.fake_resource.this
+`, out.String())
+
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, terraformCode, string(tfFile))
+	files, err := afero.ReadDir(fs, "/testTerraform")
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestCheck_PassesWhenUpToDate(t *testing.T) {
+	stubCheckEnv(t, `resource "fake_resource" this {
+  tags = {}
+}
+`)
+
+	out := new(bytes.Buffer)
+	err := check(false, context.Background(), out)
+	require.NoError(t, err)
+	assert.Equal(t, "All Terraform files are up to date with the transforms.\n", out.String())
+}
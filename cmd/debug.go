@@ -1,33 +1,55 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"github.com/Azure/golden"
 	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/peterh/liner"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
-	"os"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
+type debugFlags struct {
+	tfDir    string
+	mptfDir  string
+	evals    []string
+	evalFile string
+}
+
 func NewDebugCmd() *cobra.Command {
-	var tfDir, mptfDir string
+	flags := &debugFlags{}
 	debugCmd := &cobra.Command{
 		Use:   "debug",
-		Short: "Start REPL mode, mapotf debug --mptf-dir [path to config files]",
+		Short: "Start REPL mode, or evaluate expressions with --eval or --eval-file and print them as JSON, mapotf debug --mptf-dir [path to config files]",
 		FParseErrWhitelist: cobra.FParseErrWhitelist{
 			UnknownFlags: true,
 		},
-		RunE: replFunc(&tfDir, &mptfDir),
+		RunE: replFunc(flags),
 	}
 	pwd, err := os.Getwd()
 	if err != nil {
 		panic(fmt.Sprintf("error on getting working dir:%s", err.Error()))
 	}
-	debugCmd.Flags().StringVar(&tfDir, "tf-dir", pwd, "Terraform directory")
-	debugCmd.Flags().StringVar(&mptfDir, "mptf-dir", "", "MPTF directory, you can assign only one mptf-dir for debug command")
+	debugCmd.Flags().StringVar(&flags.tfDir, "tf-dir", pwd, "Terraform directory")
+	debugCmd.Flags().StringVar(&flags.mptfDir, "mptf-dir", "", "MPTF directory, you can assign only one mptf-dir for debug command")
+	debugCmd.Flags().StringArrayVar(&flags.evals, "eval", nil, "Evaluate an expression against the planned config and print its value as JSON on one line instead of starting the REPL. Use this option more than once to evaluate more than one expression.")
+	debugCmd.Flags().StringVar(&flags.evalFile, "eval-file", "", "Evaluate the attributes of an HCL file against the planned config and print one JSON object of their values, keyed by attribute name, instead of starting the REPL")
+	debugCmd.MarkFlagsMutuallyExclusive("eval", "eval-file")
 	err = debugCmd.MarkFlagRequired("mptf-dir")
 	if err != nil {
 		panic(err)
@@ -35,34 +57,28 @@ func NewDebugCmd() *cobra.Command {
 	return debugCmd
 }
 
-func replFunc(tfDir, mptfDir *string) func(c *cobra.Command, args []string) error {
+func replFunc(flags *debugFlags) func(c *cobra.Command, args []string) error {
 	return func(c *cobra.Command, args []string) error {
 		varFlags, err := varFlags(os.Args)
 		if err != nil {
 			return err
 		}
-		localizedDir, dispose, err := localizeConfigFolder(*mptfDir, c.Context())
+		localizedDir, dispose, err := localizeConfigFolder(flags.mptfDir, c.Context())
 		if err != nil {
 			return err
 		}
 		if dispose != nil {
 			defer dispose()
 		}
-		hclBlocks, err := pkg.LoadMPTFHclBlocks(false, localizedDir)
-		if err != nil {
-			return err
-		}
-		mod, err := pkg.NewTerraformModuleRef(*tfDir, "", "", "")
+		session, err := newDebugSession(flags.tfDir, localizedDir, varFlags, c.Context(), os.Stdout)
 		if err != nil {
 			return err
 		}
-		cfg, err := pkg.NewMetaProgrammingTFConfig(mod, nil, hclBlocks, varFlags, c.Context())
-		if err != nil {
-			return err
+		if len(flags.evals) > 0 {
+			return session.evalExpressions(flags.evals, c.OutOrStdout())
 		}
-		_, err = pkg.RunMetaProgrammingTFPlan(cfg)
-		if err != nil {
-			return err
+		if flags.evalFile != "" {
+			return session.evalFile(flags.evalFile, c.OutOrStdout())
 		}
 		line := liner.NewLiner()
 		defer func() {
@@ -70,7 +86,7 @@ func replFunc(tfDir, mptfDir *string) func(c *cobra.Command, args []string) erro
 		}()
 
 		line.SetCtrlCAborts(true)
-		fmt.Println("Entering debuging mode, press `quit` or `exit` or Ctrl+C to quit.")
+		fmt.Println("Entering debuging mode, press `quit` or `exit` or Ctrl+C to quit, `:help` lists the meta-commands.")
 
 		for {
 			if input, err := line.Prompt("debug> "); err == nil {
@@ -78,17 +94,7 @@ func replFunc(tfDir, mptfDir *string) func(c *cobra.Command, args []string) erro
 					return nil
 				}
 				line.AppendHistory(input)
-				expression, diag := hclsyntax.ParseExpression([]byte(input), "repl.hcl", hcl.InitialPos)
-				if diag.HasErrors() {
-					fmt.Printf("%s\n", diag.Error())
-					continue
-				}
-				value, diag := expression.Value(cfg.EvalContext())
-				if diag.HasErrors() {
-					fmt.Printf("%s\n", diag.Error())
-					continue
-				}
-				fmt.Println(golden.CtyValueToString(value))
+				session.eval(input)
 			} else if errors.Is(err, liner.ErrPromptAborted) {
 				fmt.Println("Aborted")
 				break
@@ -102,6 +108,277 @@ func replFunc(tfDir, mptfDir *string) func(c *cobra.Command, args []string) erro
 	}
 }
 
+// debugSession evaluates the REPL input against the plan of an mptf dir. An
+// input starting with `:` is a meta-command, anything else an expression
+// evaluated against `cfg.EvalContext()`.
+type debugSession struct {
+	moduleRef *pkg.TerraformModuleRef
+	mptfDir   string
+	varFlags  []golden.CliFlagAssignedVariables
+	ctx       context.Context
+	cfg       *pkg.MetaProgrammingTFConfig
+	out       io.Writer
+}
+
+func newDebugSession(tfDir, mptfDir string, varFlags []golden.CliFlagAssignedVariables, ctx context.Context, out io.Writer) (*debugSession, error) {
+	mod, err := pkg.NewTerraformModuleRef(tfDir, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	s := &debugSession{
+		moduleRef: mod,
+		mptfDir:   mptfDir,
+		varFlags:  varFlags,
+		ctx:       ctx,
+		out:       out,
+	}
+	s.cfg, _, err = s.plan()
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// plan loads and plans the mptf dir against the module, with a config of its
+// own, since applying transforms changes the module held by the config.
+func (s *debugSession) plan() (*pkg.MetaProgrammingTFConfig, *pkg.MetaProgrammingTFPlan, error) {
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, s.mptfDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := pkg.NewMetaProgrammingTFConfig(s.moduleRef, nil, hclBlocks, s.varFlags, cf.terraformOptions, s.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, plan, nil
+}
+
+func (s *debugSession) eval(input string) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return
+	}
+	if strings.HasPrefix(input, ":") {
+		fields := strings.Fields(input)
+		s.metaCommand(fields[0], fields[1:])
+		return
+	}
+	expression, diag := hclsyntax.ParseExpression([]byte(input), "repl.hcl", hcl.InitialPos)
+	if diag.HasErrors() {
+		_, _ = fmt.Fprintf(s.out, "%s\n", diag.Error())
+		return
+	}
+	value, diag := expression.Value(s.cfg.EvalContext())
+	if diag.HasErrors() {
+		_, _ = fmt.Fprintf(s.out, "%s\n", diag.Error())
+		return
+	}
+	_, _ = fmt.Fprintln(s.out, golden.CtyValueToString(value))
+}
+
+const debugHelp = `Meta-commands:
+  :blocks [type]         list the addresses of the Terraform blocks, optionally only the blocks of a block type like ` + "`resource`" + ` or a resource type like ` + "`azurerm_resource_group`" + `
+  :inspect <address>     print what data blocks see of the Terraform block at address, ` + "`mptf`" + ` included
+  :transform <address>   print the decoded transform at address and the diff it would make on its own, without writing
+  :help                  print this help`
+
+func (s *debugSession) metaCommand(command string, args []string) {
+	var err error
+	switch command {
+	case ":blocks":
+		if len(args) > 1 {
+			err = fmt.Errorf("usage: :blocks [type]")
+			break
+		}
+		s.listBlocks(args)
+	case ":inspect":
+		if len(args) != 1 {
+			err = fmt.Errorf("usage: :inspect <address>")
+			break
+		}
+		err = s.inspect(args[0])
+	case ":transform":
+		if len(args) != 1 {
+			err = fmt.Errorf("usage: :transform <address>")
+			break
+		}
+		err = s.previewTransform(args[0])
+	case ":help":
+		_, _ = fmt.Fprintln(s.out, debugHelp)
+	default:
+		err = fmt.Errorf("unknown meta-command %s, `:help` lists the meta-commands", command)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(s.out, err.Error())
+	}
+}
+
+// listBlocks prints the sorted addresses of the root blocks of the module, or
+// of those whose block type or first label is args[0].
+func (s *debugSession) listBlocks(args []string) {
+	var addresses []string
+	for _, b := range s.cfg.RootBlocks() {
+		if len(args) == 1 && b.Type != args[0] && (len(b.Labels) == 0 || b.Labels[0] != args[0]) {
+			continue
+		}
+		addresses = append(addresses, b.Address)
+	}
+	if len(addresses) == 0 {
+		_, _ = fmt.Fprintln(s.out, "No blocks.")
+		return
+	}
+	sort.Strings(addresses)
+	_, _ = fmt.Fprintln(s.out, strings.Join(addresses, "\n"))
+}
+
+func (s *debugSession) inspect(address string) error {
+	b := s.cfg.RootBlock(address)
+	if b == nil {
+		return fmt.Errorf("no block with address %s, `:blocks` lists them", address)
+	}
+	_, _ = fmt.Fprintln(s.out, prettyValue(b.EvalContext()))
+	return nil
+}
+
+// previewTransform applies the transforms at address to a fresh plan in a
+// sandbox. Without a `[key]`, it applies every instance, and the key may be
+// quoted or not. It prints the decoded transforms, then the diff of every
+// file they would change. Transforms are applied on their own, without the
+// ones ordered before them.
+func (s *debugSession) previewTransform(address string) error {
+	sandbox := filesystem.NewSandbox(filesystem.Fs)
+	realFs := filesystem.Fs
+	filesystem.Fs = sandbox
+	defer func() {
+		filesystem.Fs = realFs
+	}()
+	_, plan, err := s.plan()
+	if err != nil {
+		return err
+	}
+	address = strings.ReplaceAll(address, `"`, "")
+	var transforms []pkg.Transform
+	for _, t := range plan.Transforms {
+		base, _, _ := strings.Cut(t.Address(), "[")
+		if t.Address() == address || base == address {
+			transforms = append(transforms, t)
+		}
+	}
+	if len(transforms) == 0 {
+		return fmt.Errorf("no transform with address %s", address)
+	}
+	plan.Transforms = transforms
+	if err = plan.Apply(); err != nil {
+		return err
+	}
+	for _, t := range transforms {
+		_, _ = fmt.Fprintf(s.out, "%s:\n%s\n", t.Address(), golden.BlockToString(t))
+	}
+	changes, err := sandbox.Changes()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(s.out, "No changes.")
+		return nil
+	}
+	for _, c := range changes {
+		name := c.Path
+		if rel, err := filepath.Rel(s.moduleRef.AbsDir, c.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+		diff, err := c.UnifiedDiff(filepath.ToSlash(name))
+		if err != nil {
+			return fmt.Errorf("cannot render diff for %s: %+v", c.Path, err)
+		}
+		_, _ = fmt.Fprint(s.out, diff)
+	}
+	return nil
+}
+
+// evalExpressions evaluates every expression and writes its value to out as
+// JSON, one line per expression, stopping at the first error.
+func (s *debugSession) evalExpressions(expressions []string, out io.Writer) error {
+	for _, e := range expressions {
+		expression, diag := hclsyntax.ParseExpression([]byte(e), "eval.hcl", hcl.InitialPos)
+		if diag.HasErrors() {
+			return fmt.Errorf("cannot parse `%s`: %s", e, diag.Error())
+		}
+		value, err := s.evalJSON(expression)
+		if err != nil {
+			return fmt.Errorf("cannot evaluate `%s`: %+v", e, err)
+		}
+		_, _ = fmt.Fprintln(out, string(value))
+	}
+	return nil
+}
+
+// evalFile evaluates the attributes of the HCL file at path and writes one
+// JSON object of their values, keyed by attribute name, to out.
+func (s *debugSession) evalFile(path string, out io.Writer) error {
+	content, err := afero.ReadFile(filesystem.Fs, path)
+	if err != nil {
+		return fmt.Errorf("cannot read %s: %+v", path, err)
+	}
+	file, diag := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+	if diag.HasErrors() {
+		return diag
+	}
+	attributes, diag := file.Body.JustAttributes()
+	if diag.HasErrors() {
+		return diag
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for i, name := range names {
+		value, err := s.evalJSON(attributes[name].Expr)
+		if err != nil {
+			return fmt.Errorf("cannot evaluate %s(%s): %+v", name, attributes[name].Range.String(), err)
+		}
+		key, _ := json.Marshal(name)
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, "%s:%s", key, value)
+	}
+	sb.WriteString("}")
+	_, _ = fmt.Fprintln(out, sb.String())
+	return nil
+}
+
+// evalJSON evaluates expression against the planned config and marshals the
+// value with its own type, so objects and tuples keep their shape.
+func (s *debugSession) evalJSON(expression hcl.Expression) ([]byte, error) {
+	value, diag := expression.Value(s.cfg.EvalContext())
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	if !value.IsWhollyKnown() {
+		return nil, fmt.Errorf("value is not known")
+	}
+	if value.IsNull() {
+		return []byte("null"), nil
+	}
+	return ctyjson.Marshal(value, value.Type())
+}
+
+// prettyValue renders v as a formatted HCL value, one attribute per line.
+func prettyValue(v cty.Value) string {
+	if !v.IsWhollyKnown() {
+		return golden.CtyValueToString(v)
+	}
+	return string(hclwrite.Format(hclwrite.TokensForValue(v).Bytes()))
+}
+
 func init() {
 	rootCmd.AddCommand(NewDebugCmd())
 }
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const debugTerraformCode = `resource "fake_resource" this {
+  name = "this"
+}
+
+resource "fake_resource" that {
+}
+
+resource "other_resource" this {
+}
+
+variable "location" {
+}
+`
+
+const debugMptfConfig = `data "resource" fake {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" tags {
+  for_each             = try(data.resource.fake.result.fake_resource, {})
+  target_block_address = each.value.mptf.block_address
+  asstring {
+    tags = "{ owner = \"${each.key}\" }"
+  }
+}
+`
+
+func newStubDebugSession(t *testing.T) (*debugSession, *bytes.Buffer, afero.Fs) {
+	fs := stubTransformEnv(t, debugMptfConfig, debugTerraformCode)
+	out := new(bytes.Buffer)
+	s, err := newDebugSession("/testTerraform", "/testData", nil, context.Background(), out)
+	require.NoError(t, err)
+	return s, out, fs
+}
+
+func TestDebugSession_Expression(t *testing.T) {
+	s, out, _ := newStubDebugSession(t)
+
+	s.eval(`sort(keys(data.resource.fake.result.fake_resource))`)
+	assert.Equal(t, "[that, this]\n", out.String())
+}
+
+func TestDebugSession_Blocks(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    ":blocks",
+			expected: "resource.fake_resource.that\nresource.fake_resource.this\nresource.other_resource.this\nvariable.location\n",
+		},
+		{
+			input:    ":blocks fake_resource",
+			expected: "resource.fake_resource.that\nresource.fake_resource.this\n",
+		},
+		{
+			input:    ":blocks variable",
+			expected: "variable.location\n",
+		},
+		{
+			input:    ":blocks output",
+			expected: "No blocks.\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			s, out, _ := newStubDebugSession(t)
+			s.eval(c.input)
+			assert.Equal(t, c.expected, out.String())
+		})
+	}
+}
+
+func TestDebugSession_Inspect(t *testing.T) {
+	s, out, _ := newStubDebugSession(t)
+
+	s.eval(":inspect resource.fake_resource.this")
+	assert.Contains(t, out.String(), `name = "this"`)
+	assert.Contains(t, out.String(), `block_address = "resource.fake_resource.this"`)
+
+	out.Reset()
+	s.eval(":inspect resource.fake_resource.missing")
+	assert.Contains(t, out.String(), "no block with address resource.fake_resource.missing")
+}
+
+func TestDebugSession_TransformPrintsDiffWithoutWriting(t *testing.T) {
+	s, out, fs := newStubDebugSession(t)
+
+	s.eval(`:transform transform.update_in_place.tags["this"]`)
+	assert.Contains(t, out.String(), "transform.update_in_place.tags[this]:")
+	assert.Contains(t, out.String(), "--- a/main.tf")
+	assert.Contains(t, out.String(), `+  tags = { owner = "this" }`)
+	assert.NotContains(t, out.String(), `owner = "that"`)
+	content, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, debugTerraformCode, string(content))
+
+	out.Reset()
+	s.eval(":transform transform.update_in_place.tags")
+	assert.Contains(t, out.String(), `owner = "this"`)
+	assert.Contains(t, out.String(), `owner = "that"`)
+}
+
+func TestDebugSession_TransformLeavesSessionUnchanged(t *testing.T) {
+	s, out, _ := newStubDebugSession(t)
+
+	s.eval(":transform transform.update_in_place.tags")
+	out.Reset()
+	s.eval(":inspect resource.fake_resource.this")
+	assert.NotContains(t, out.String(), "tags")
+}
+
+func TestDebugSession_UnknownTransform(t *testing.T) {
+	s, out, _ := newStubDebugSession(t)
+
+	s.eval(":transform transform.update_in_place.missing")
+	assert.Equal(t, "no transform with address transform.update_in_place.missing\n", out.String())
+}
+
+func TestDebugSession_UnknownMetaCommand(t *testing.T) {
+	s, out, _ := newStubDebugSession(t)
+
+	s.eval(":unknown")
+	assert.Contains(t, out.String(), "unknown meta-command :unknown")
+}
+
+func TestDebugSession_EvalExpressionsPrintsJSONLines(t *testing.T) {
+	s, _, _ := newStubDebugSession(t)
+
+	out := new(bytes.Buffer)
+	err := s.evalExpressions([]string{
+		`sort(keys(data.resource.fake.result.fake_resource))`,
+		`[for r in data.resource.fake.result.fake_resource : r.mptf.block_address if !contains(keys(r), "name")]`,
+		`{ count = length(data.resource.fake.result.fake_resource), empty = null }`,
+		`null`,
+	}, out)
+	require.NoError(t, err)
+	assert.Equal(t, `["that","this"]
+["resource.fake_resource.that"]
+{"count":2,"empty":null}
+null
+`, out.String())
+}
+
+func TestDebugSession_EvalExpressionsFailsOnError(t *testing.T) {
+	s, _, _ := newStubDebugSession(t)
+
+	out := new(bytes.Buffer)
+	err := s.evalExpressions([]string{`1`, `data.resource.missing`, `2`}, out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "data.resource.missing")
+	assert.Equal(t, "1\n", out.String())
+}
+
+func TestDebugSession_EvalFilePrintsOneObject(t *testing.T) {
+	s, _, fs := newStubDebugSession(t)
+	require.NoError(t, afero.WriteFile(fs, "/queries.hcl", []byte(`untagged = [for r in data.resource.fake.result.fake_resource : r.mptf.block_address if !contains(keys(r), "tags")]
+addresses = sort([for r in data.resource.fake.result.fake_resource : r.mptf.block_address])
+`), 0644))
+
+	out := new(bytes.Buffer)
+	require.NoError(t, s.evalFile("/queries.hcl", out))
+	assert.JSONEq(t, `{
+  "addresses": ["resource.fake_resource.that", "resource.fake_resource.this"],
+  "untagged": ["resource.fake_resource.that", "resource.fake_resource.this"]
+}`, out.String())
+}
+
+func TestDebugSession_EvalFileRejectsBlocks(t *testing.T) {
+	s, _, fs := newStubDebugSession(t)
+	require.NoError(t, afero.WriteFile(fs, "/queries.hcl", []byte("locals {\n  a = 1\n}\n"), 0644))
+
+	err := s.evalFile("/queries.hcl", new(bytes.Buffer))
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/Azure/mapotf/pkg"
+	"github.com/Azure/mapotf/pkg/sarif"
+	"github.com/spf13/cobra"
+)
+
+// lintFinding is a pkg.Finding located for output.
+type lintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	located
+}
+
+func newLintFinding(m *pkg.TerraformModuleRef, f pkg.Finding) lintFinding {
+	return lintFinding{
+		Rule:     f.Rule,
+		Severity: f.Severity,
+		Message:  f.Message,
+		located:  newLocated(f.BlockAddress, m.Key, m.Dir, f.Range),
+	}
+}
+
+func (f lintFinding) location() located {
+	return f.located
+}
+
+func (f lintFinding) ruleID() string {
+	return f.Rule
+}
+
+func (f lintFinding) tableRow() string {
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s", f.Severity, f.Rule, f.Address, f.lines(), f.Message)
+}
+
+// sarifResult reports the finding as a result of the rule it violates, at the
+// severity of that rule.
+func (f lintFinding) sarifResult(root string) sarif.Result {
+	return sarif.Result{
+		RuleID:    f.Rule,
+		Level:     f.Severity,
+		Message:   sarif.Message{Text: f.Message},
+		Locations: []sarif.Location{f.sarifLocation(root)},
+	}
+}
+
+func NewLintCmd() *cobra.Command {
+	recursive := false
+	format := formatTable
+
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Report the Terraform blocks targeted by rule blocks, mapotf lint [-r] [--format table|json|sarif] --tf-dir [] --mptf-dir [path to config files], support mutilple mptf dirs",
+		FParseErrWhitelist: cobra.FParseErrWhitelist{
+			UnknownFlags: true,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lint(recursive, format, cmd.Context(), cmd.OutOrStdout())
+		},
+	}
+
+	lintCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Lint all modules or not, default to the root module only.")
+	lintCmd.Flags().StringVar(&format, "format", formatTable, "Output format: `table`, `json` or `sarif`")
+	return lintCmd
+}
+
+// lint plans the mptf dirs against every module and writes the findings of
+// their `rule` blocks to out in format. Transforms are planned but never
+// applied. It fails after writing the findings when any of them is an error.
+func lint(recursive bool, format string, ctx context.Context, out io.Writer) error {
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	varFlags, err := varFlags(os.Args)
+	if err != nil {
+		return err
+	}
+	mptfDirs, dispose, err := localizeMptfDirs(ctx)
+	defer dispose()
+	if err != nil {
+		return err
+	}
+	moduleRefs, err := loadModuleRefs(recursive)
+	if err != nil {
+		return err
+	}
+	var findings []lintFinding
+	for _, m := range moduleRefs {
+		for _, mptfDir := range mptfDirs {
+			hclBlocks, err := pkg.LoadMPTFHclBlocks(false, mptfDir)
+			if err != nil {
+				return err
+			}
+			cfg, err := pkg.NewMetaProgrammingTFConfig(m, &cf.tfDir, hclBlocks, varFlags, cf.terraformOptions, ctx)
+			if err != nil {
+				return err
+			}
+			if _, err = pkg.RunMetaProgrammingTFPlan(cfg); err != nil {
+				return err
+			}
+			for _, f := range cfg.Findings() {
+				findings = append(findings, newLintFinding(m, f))
+			}
+		}
+	}
+	findings = sortResults(findings)
+	if err = writeResults(out, format, findings, "SEVERITY\tRULE\tADDRESS\tLOCATION\tMESSAGE", "No findings.", lintRules(findings)); err != nil {
+		return err
+	}
+	errorCount := 0
+	for _, f := range findings {
+		if f.Severity == pkg.SeverityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		return fmt.Errorf("%d error(s) found", errorCount)
+	}
+	return nil
+}
+
+// lintRules declares every rule of findings as a SARIF rule at its severity.
+func lintRules(findings []lintFinding) []sarif.Rule {
+	var rules []sarif.Rule
+	seen := make(map[string]struct{})
+	for _, f := range findings {
+		if _, ok := seen[f.Rule]; ok {
+			continue
+		}
+		seen[f.Rule] = struct{}{}
+		rules = append(rules, sarif.Rule{
+			ID:                   f.Rule,
+			DefaultConfiguration: &sarif.Configuration{Level: f.Severity},
+		})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+func init() {
+	rootCmd.AddCommand(NewLintCmd())
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lintTerraformCode = `resource "azurerm_storage_account" "secure" {
+  min_tls_version = "TLS1_2"
+}
+
+resource "azurerm_storage_account" "legacy" {
+  name = "legacy"
+}
+
+variable "location" {
+}
+`
+
+const lintConfig = `data "resource" "storage" {
+  resource_type = "azurerm_storage_account"
+}
+
+rule "min_tls_version" {
+  severity = "error"
+  message  = "Storage accounts must set min_tls_version"
+  targets  = [for r in try(data.resource.storage.result.azurerm_storage_account, {}) : r if !contains(keys(r), "min_tls_version")]
+}
+
+rule "location_description" {
+  message = "Variables should have a description"
+  targets = ["variable.location"]
+}
+`
+
+func TestLint_TableFailsOnErrors(t *testing.T) {
+	stubTransformEnv(t, lintConfig, lintTerraformCode)
+
+	out := new(bytes.Buffer)
+	err := lint(false, formatTable, context.Background(), out)
+	require.Error(t, err)
+	assert.Equal(t, "1 error(s) found", err.Error())
+	assert.Equal(t, `SEVERITY  RULE                  ADDRESS                                  LOCATION      MESSAGE
+error     min_tls_version       resource.azurerm_storage_account.legacy  main.tf:5-7   Storage accounts must set min_tls_version
+warning   location_description  variable.location                        main.tf:9-10  Variables should have a description
+`, out.String())
+}
+
+func TestLint_WarningsOnlySucceed(t *testing.T) {
+	stubTransformEnv(t, `rule "location_description" {
+  severity = "warning"
+  message  = "Variables should have a description"
+  targets  = ["variable.location"]
+}
+`, lintTerraformCode)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, lint(false, formatTable, context.Background(), out))
+	assert.Contains(t, out.String(), "location_description")
+}
+
+func TestLint_NoFindings(t *testing.T) {
+	stubTransformEnv(t, `rule "none" {
+  message = "found"
+  targets = []
+}
+`, lintTerraformCode)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, lint(false, formatTable, context.Background(), out))
+	assert.Equal(t, "No findings.\n", out.String())
+}
+
+func TestLint_JSON(t *testing.T) {
+	stubTransformEnv(t, lintConfig, lintTerraformCode)
+
+	out := new(bytes.Buffer)
+	require.Error(t, lint(false, formatJSON, context.Background(), out))
+	var findings []lintFinding
+	require.NoError(t, json.Unmarshal(out.Bytes(), &findings))
+	assert.Equal(t, []lintFinding{
+		{
+			Rule:     "min_tls_version",
+			Severity: "error",
+			Message:  "Storage accounts must set min_tls_version",
+			located: located{
+				Address:     "resource.azurerm_storage_account.legacy",
+				File:        "main.tf",
+				StartLine:   5,
+				StartColumn: 1,
+				EndLine:     7,
+				EndColumn:   2,
+			},
+		},
+		{
+			Rule:     "location_description",
+			Severity: "warning",
+			Message:  "Variables should have a description",
+			located: located{
+				Address:     "variable.location",
+				File:        "main.tf",
+				StartLine:   9,
+				StartColumn: 1,
+				EndLine:     10,
+				EndColumn:   2,
+			},
+		},
+	}, findings)
+}
+
+func TestLint_Sarif(t *testing.T) {
+	stubTransformEnv(t, lintConfig, lintTerraformCode)
+
+	out := new(bytes.Buffer)
+	require.Error(t, lint(false, formatSarif, context.Background(), out))
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID                   string `json:"id"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+							EndLine   int `json:"endLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	rules := log.Runs[0].Tool.Driver.Rules
+	require.Len(t, rules, 2)
+	assert.Equal(t, "location_description", rules[0].ID)
+	assert.Equal(t, "warning", rules[0].DefaultConfiguration.Level)
+	assert.Equal(t, "min_tls_version", rules[1].ID)
+	assert.Equal(t, "error", rules[1].DefaultConfiguration.Level)
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Equal(t, "min_tls_version", results[0].RuleID)
+	assert.Equal(t, "error", results[0].Level)
+	assert.Equal(t, "Storage accounts must set min_tls_version", results[0].Message.Text)
+	location := results[0].Locations[0].PhysicalLocation
+	assert.Equal(t, "main.tf", location.ArtifactLocation.URI)
+	assert.Equal(t, 5, location.Region.StartLine)
+	assert.Equal(t, 7, location.Region.EndLine)
+}
+
+func TestLint_MultipleMptfDirs(t *testing.T) {
+	fs := stubTransformEnv(t, lintConfig, lintTerraformCode)
+	require.NoError(t, afero.WriteFile(fs, "/testData2/main.mptf.hcl", []byte(`rule "all_variables" {
+  severity = "note"
+  message  = "variable"
+  targets  = ["variable.location"]
+}
+`), 0644))
+	cf.mptfDirs = append(cf.mptfDirs, "/testData2")
+
+	out := new(bytes.Buffer)
+	require.Error(t, lint(false, formatTable, context.Background(), out))
+	assert.Contains(t, out.String(), "min_tls_version")
+	assert.Contains(t, out.String(), "all_variables")
+}
+
+func TestLint_DoesNotApplyTransforms(t *testing.T) {
+	fs := stubTransformEnv(t, lintConfig+`
+transform "remove_block" location {
+  target_block_address = "variable.location"
+}
+`, lintTerraformCode)
+
+	require.Error(t, lint(false, formatTable, context.Background(), new(bytes.Buffer)))
+	content, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, lintTerraformCode, string(content))
+}
+
+func TestLint_UnknownFormat(t *testing.T) {
+	stubTransformEnv(t, lintConfig, lintTerraformCode)
+
+	err := lint(false, "xml", context.Background(), new(bytes.Buffer))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown format "xml"`)
+}
@@ -0,0 +1,25 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/Azure/mapotf/pkg/lsp"
+	"github.com/spf13/cobra"
+)
+
+func NewLspCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "lsp",
+		Short: "Start a language server for `.mptf.hcl` files over stdio, offering completion, hover, go to definition and diagnostics, mapotf lsp --tf-dir [path to terraform config]",
+		FParseErrWhitelist: cobra.FParseErrWhitelist{
+			UnknownFlags: true,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lsp.NewServer(cf.tfDir, cf.terraformOptions, os.Stdin, cmd.OutOrStdout()).Serve(cmd.Context())
+		},
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(NewLspCmd())
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"os/exec"
+	"path/filepath"
+
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+var lookPath = exec.LookPath
+
+// newTerraformOptions builds the CLI binary and load options of this run
+// from the flags. With `--opentofu` every shelled-out CLI call goes to
+// `tofu`; without the flag OpenTofu mode is auto-detected, see
+// detectOpenTofu.
+func newTerraformOptions(cmd *cobra.Command) pkg.TerraformOptions {
+	enabled := cf.openTofu
+	if !cmd.Flags().Changed("opentofu") {
+		enabled = detectOpenTofu(cf.tfDir)
+	}
+	binary := "terraform"
+	if enabled {
+		binary = "tofu"
+	}
+	return pkg.TerraformOptions{
+		Binary: binary,
+		LoadOptions: terraform.LoadOptions{
+			OpenTofu:         enabled,
+			IncludeOverrides: cf.includeOverrides,
+		},
+	}
+}
+
+// detectOpenTofu reports whether tfDir looks like an OpenTofu module: it
+// contains `.tofu` or `.tofu.json` files, or `tofu` is on PATH while
+// `terraform` is not.
+func detectOpenTofu(tfDir string) bool {
+	for _, pattern := range []string{"*.tofu", "*.tofu.json"} {
+		matches, err := afero.Glob(filesystem.Fs, filepath.Join(tfDir, pattern))
+		if err == nil && len(matches) > 0 {
+			return true
+		}
+	}
+	if _, err := lookPath("terraform"); err == nil {
+		return false
+	}
+	_, err := lookPath("tofu")
+	return err == nil
+}
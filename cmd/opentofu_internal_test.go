@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectOpenTofu(t *testing.T) {
+	cases := []struct {
+		desc     string
+		files    []string
+		binaries []string
+		expected bool
+	}{
+		{
+			desc:     "tofu files",
+			files:    []string{"main.tf", "main.tofu"},
+			binaries: []string{"terraform", "tofu"},
+			expected: true,
+		},
+		{
+			desc:     "tofu json files",
+			files:    []string{"main.tofu.json"},
+			binaries: []string{"terraform"},
+			expected: true,
+		},
+		{
+			desc:     "only tofu on path",
+			files:    []string{"main.tf"},
+			binaries: []string{"tofu"},
+			expected: true,
+		},
+		{
+			desc:     "both binaries on path",
+			files:    []string{"main.tf"},
+			binaries: []string{"terraform", "tofu"},
+			expected: false,
+		},
+		{
+			desc:     "no binary on path",
+			files:    []string{"main.tf"},
+			expected: false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			for _, f := range c.files {
+				_ = afero.WriteFile(fs, filepath.Join("tf", f), []byte(""), 0644)
+			}
+			stub := gostub.Stub(&filesystem.Fs, fs)
+			defer stub.Reset()
+			stub.Stub(&lookPath, func(file string) (string, error) {
+				for _, b := range c.binaries {
+					if b == file {
+						return filepath.Join("bin", file), nil
+					}
+				}
+				return "", errors.New("not found")
+			})
+			assert.Equal(t, c.expected, detectOpenTofu("tf"))
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/Azure/mapotf/pkg/sarif"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// expressionQueryName names the query given by `--expression`.
+const expressionQueryName = "expression"
+
+// builtinQueryBlocks are the data blocks an `--expression` is evaluated
+// against when no mptf dir is set.
+const builtinQueryBlocks = `data "resource" "all" {
+}
+
+data "data" "all" {
+}
+`
+
+// queryOutput is an `output` block of a query dir. Its value lists the
+// matched Terraform blocks.
+type queryOutput struct {
+	Name        string
+	Value       hcl.Expression `hcl:"value"`
+	Description string         `hcl:"description,optional"`
+}
+
+// queryMatch is one Terraform block a query matched.
+type queryMatch struct {
+	Query string `json:"query"`
+	located
+}
+
+func (m queryMatch) location() located {
+	return m.located
+}
+
+func (m queryMatch) ruleID() string {
+	return m.Query
+}
+
+func (m queryMatch) tableRow() string {
+	return fmt.Sprintf("%s\t%s\t%s", m.Query, m.Address, m.lines())
+}
+
+// sarifResult reports the match as a note of the rule named after its output.
+func (m queryMatch) sarifResult(root string) sarif.Result {
+	return sarif.Result{
+		RuleID:    m.Query,
+		Level:     sarif.LevelNote,
+		Message:   sarif.Message{Text: fmt.Sprintf("%s matches query %s", m.Address, m.Query)},
+		Locations: []sarif.Location{m.sarifLocation(root)},
+	}
+}
+
+func NewQueryCmd() *cobra.Command {
+	recursive := false
+	expression := ""
+	format := formatTable
+
+	queryCmd := &cobra.Command{
+		Use:   "query",
+		Short: "Find Terraform blocks with data blocks, mapotf query [-r] [--format table|json|sarif] --tf-dir [] (--mptf-dir [path to data, locals and output blocks] | --expression [expression])",
+		FParseErrWhitelist: cobra.FParseErrWhitelist{
+			UnknownFlags: true,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return query(recursive, expression, format, cmd.Context(), cmd.OutOrStdout())
+		},
+	}
+
+	queryCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Query all modules or not, default to the root module only.")
+	queryCmd.Flags().StringVar(&expression, "expression", "", "Query with this expression instead of the `output` blocks of the mptf dir. Without an mptf dir it can reference `data.resource.all` and `data.data.all`, which match every resource and data source.")
+	queryCmd.Flags().StringVar(&format, "format", formatTable, "Output format: `table`, `json` or `sarif`")
+	return queryCmd
+}
+
+// query evaluates the `output` blocks of the mptf dir, or expression, against
+// every module and writes the matched blocks to out in format.
+func query(recursive bool, expression, format string, ctx context.Context, out io.Writer) error {
+	if err := checkFormat(format); err != nil {
+		return err
+	}
+	if len(cf.mptfDirs) > 1 {
+		return fmt.Errorf("query takes one mptf dir, got %d", len(cf.mptfDirs))
+	}
+	if len(cf.mptfDirs) == 0 && expression == "" {
+		return fmt.Errorf("query needs an mptf dir with `output` blocks or an `--expression`")
+	}
+	varFlags, err := varFlags(os.Args)
+	if err != nil {
+		return err
+	}
+	mptfDirs, dispose, err := localizeMptfDirs(ctx)
+	defer dispose()
+	if err != nil {
+		return err
+	}
+	moduleRefs, err := loadModuleRefs(recursive)
+	if err != nil {
+		return err
+	}
+	hclBlocks, outputs, err := loadQuery(mptfDirs, expression)
+	if err != nil {
+		return err
+	}
+	var matches []queryMatch
+	for _, m := range moduleRefs {
+		cfg, err := pkg.NewMetaProgrammingTFConfig(m, &cf.tfDir, cloneHclBlocks(hclBlocks), varFlags, cf.terraformOptions, ctx)
+		if err != nil {
+			return err
+		}
+		if _, err = pkg.RunMetaProgrammingTFPlan(cfg); err != nil {
+			return err
+		}
+		for _, o := range outputs {
+			value, diag := o.Value.Value(cfg.EvalContext())
+			if diag.HasErrors() {
+				return fmt.Errorf("cannot evaluate output %s: %+v", o.Name, diag)
+			}
+			found, err := queryMatches(cfg, m, o.Name, value)
+			if err != nil {
+				return fmt.Errorf("output %s: %+v", o.Name, err)
+			}
+			matches = append(matches, found...)
+		}
+	}
+	return writeResults(out, format, sortResults(matches), "QUERY\tADDRESS\tLOCATION", "No matches.", queryRules(outputs))
+}
+
+// loadQuery returns the blocks to plan and the outputs to evaluate: the data,
+// locals, variable and output blocks of the mptf dir, or the expression with
+// the built-in data blocks when there is no mptf dir. Transforms are refused,
+// since a query never changes code.
+func loadQuery(mptfDirs []string, expression string) ([]*golden.HclBlock, []*queryOutput, error) {
+	var hclBlocks []*golden.HclBlock
+	var outputs []*queryOutput
+	var err error
+	if len(mptfDirs) == 0 {
+		if hclBlocks, err = parseMptfHclBlocks([]byte(builtinQueryBlocks), "query.mptf.hcl"); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		dir := mptfDirs[0]
+		if hclBlocks, err = pkg.LoadMPTFHclBlocks(true, dir); err != nil {
+			return nil, nil, err
+		}
+		for _, b := range hclBlocks {
+			if b.Type == "transform" {
+				return nil, nil, fmt.Errorf("query dirs cannot declare transforms, got %s", b.Range().String())
+			}
+		}
+		if outputs, err = loadQueryOutputs(dir); err != nil {
+			return nil, nil, err
+		}
+	}
+	if expression != "" {
+		expr, diag := hclsyntax.ParseExpression([]byte(expression), "expression.hcl", hcl.InitialPos)
+		if diag.HasErrors() {
+			return nil, nil, fmt.Errorf("cannot parse `%s`: %s", expression, diag.Error())
+		}
+		outputs = []*queryOutput{{Name: expressionQueryName, Value: expr}}
+	}
+	if len(outputs) == 0 {
+		return nil, nil, fmt.Errorf("no `output` block found at %s", mptfDirs[0])
+	}
+	return hclBlocks, outputs, nil
+}
+
+// loadQueryOutputs decodes the `output` blocks of the `.mptf.hcl` files of
+// dir and rejects any other block golden doesn't know.
+func loadQueryOutputs(dir string) ([]*queryOutput, error) {
+	matches, err := afero.Glob(filesystem.Fs, filepath.Join(dir, "*.mptf.hcl"))
+	if err != nil {
+		return nil, err
+	}
+	var outputs []*queryOutput
+	names := make(map[string]struct{})
+	for _, filename := range matches {
+		content, err := afero.ReadFile(filesystem.Fs, filename)
+		if err != nil {
+			return nil, err
+		}
+		file, diag := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+		if diag.HasErrors() {
+			return nil, diag
+		}
+		for _, b := range file.Body.(*hclsyntax.Body).Blocks {
+			// `locals` blocks are split into one `local` block per attribute.
+			if b.Type == "locals" || golden.IsBlockTypeWanted(b.Type) {
+				continue
+			}
+			if b.Type != "output" {
+				return nil, fmt.Errorf("invalid block type: %s %s", b.Type, b.Range().String())
+			}
+			o := &queryOutput{}
+			if diag = gohcl.DecodeBody(b.Body, nil, o); diag.HasErrors() {
+				return nil, diag
+			}
+			if len(b.Labels) != 1 {
+				return nil, fmt.Errorf("output block needs a name, got %s", b.Range().String())
+			}
+			o.Name = b.Labels[0]
+			if _, ok := names[o.Name]; ok {
+				return nil, fmt.Errorf("duplicate output %q %s", o.Name, b.Range().String())
+			}
+			names[o.Name] = struct{}{}
+			outputs = append(outputs, o)
+		}
+	}
+	return outputs, nil
+}
+
+// cloneHclBlocks copies hclBlocks for one more config: decoding a golden
+// block evaluates its nested blocks in place.
+func cloneHclBlocks(hclBlocks []*golden.HclBlock) []*golden.HclBlock {
+	r := make([]*golden.HclBlock, 0, len(hclBlocks))
+	for _, b := range hclBlocks {
+		r = append(r, golden.CloneHclBlock(b))
+	}
+	return r
+}
+
+func parseMptfHclBlocks(content []byte, filename string) ([]*golden.HclBlock, error) {
+	readFile, diag := hclsyntax.ParseConfig(content, filename, hcl.InitialPos)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	writeFile, _ := hclwrite.ParseConfig(content, filename, hcl.InitialPos)
+	return golden.AsHclBlocks(readFile.Body.(*hclsyntax.Body).Blocks, writeFile.Body().Blocks()), nil
+}
+
+// queryMatches collects the Terraform blocks value refers to, see
+// MetaProgrammingTFConfig.TargetBlocks.
+func queryMatches(cfg *pkg.MetaProgrammingTFConfig, m *pkg.TerraformModuleRef, name string, value cty.Value) ([]queryMatch, error) {
+	blocks, err := cfg.TargetBlocks(value)
+	if err != nil {
+		return nil, err
+	}
+	var r []queryMatch
+	for _, b := range blocks {
+		r = append(r, queryMatch{
+			Query:   name,
+			located: newLocated(b.Address, m.Key, m.Dir, b.Range()),
+		})
+	}
+	return r, nil
+}
+
+// queryRules declares every output as a SARIF rule.
+func queryRules(outputs []*queryOutput) []sarif.Rule {
+	var rules []sarif.Rule
+	for _, o := range outputs {
+		rule := sarif.Rule{ID: o.Name}
+		if o.Description != "" {
+			rule.ShortDescription = &sarif.Message{Text: o.Description}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func init() {
+	rootCmd.AddCommand(NewQueryCmd())
+}
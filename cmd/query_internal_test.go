@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const queryTerraformCode = `resource "azurerm_storage_account" "secure" {
+  min_tls_version = "TLS1_2"
+}
+
+resource "azurerm_storage_account" "legacy" {
+  name = "legacy"
+}
+
+resource "azurerm_resource_group" "this" {
+}
+`
+
+const queryConfig = `data "resource" "storage" {
+  resource_type = "azurerm_storage_account"
+}
+
+locals {
+  storage_accounts = try(data.resource.storage.result.azurerm_storage_account, {})
+}
+
+output "no_min_tls_version" {
+  description = "Storage accounts without min_tls_version"
+  value       = [for r in local.storage_accounts : r if !contains(keys(r), "min_tls_version")]
+}
+
+output "resource_group" {
+  value = ["resource.azurerm_resource_group.this"]
+}
+`
+
+func TestQuery_Table(t *testing.T) {
+	stubTransformEnv(t, queryConfig, queryTerraformCode)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, query(false, "", formatTable, context.Background(), out))
+	assert.Equal(t, `QUERY               ADDRESS                                  LOCATION
+no_min_tls_version  resource.azurerm_storage_account.legacy  main.tf:5-7
+resource_group      resource.azurerm_resource_group.this     main.tf:9-10
+`, out.String())
+}
+
+func TestQuery_JSON(t *testing.T) {
+	stubTransformEnv(t, queryConfig, queryTerraformCode)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, query(false, "", formatJSON, context.Background(), out))
+	var matches []queryMatch
+	require.NoError(t, json.Unmarshal(out.Bytes(), &matches))
+	assert.Equal(t, []queryMatch{
+		{
+			Query: "no_min_tls_version",
+			located: located{
+				Address:     "resource.azurerm_storage_account.legacy",
+				File:        "main.tf",
+				StartLine:   5,
+				StartColumn: 1,
+				EndLine:     7,
+				EndColumn:   2,
+			},
+		},
+		{
+			Query: "resource_group",
+			located: located{
+				Address:     "resource.azurerm_resource_group.this",
+				File:        "main.tf",
+				StartLine:   9,
+				StartColumn: 1,
+				EndLine:     10,
+				EndColumn:   2,
+			},
+		},
+	}, matches)
+}
+
+func TestQuery_Sarif(t *testing.T) {
+	stubTransformEnv(t, queryConfig, queryTerraformCode)
+
+	out := new(bytes.Buffer)
+	require.NoError(t, query(false, "", formatSarif, context.Background(), out))
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID               string `json:"id"`
+						ShortDescription *struct {
+							Text string `json:"text"`
+						} `json:"shortDescription"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	require.Len(t, log.Runs, 1)
+	rules := log.Runs[0].Tool.Driver.Rules
+	require.Len(t, rules, 2)
+	assert.Equal(t, "no_min_tls_version", rules[0].ID)
+	assert.Equal(t, "Storage accounts without min_tls_version", rules[0].ShortDescription.Text)
+	assert.Nil(t, rules[1].ShortDescription)
+	results := log.Runs[0].Results
+	require.Len(t, results, 2)
+	assert.Equal(t, "no_min_tls_version", results[0].RuleID)
+	assert.Equal(t, "main.tf", results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 5, results[0].Locations[0].PhysicalLocation.Region.StartLine)
+}
+
+func TestQuery_ExpressionWithoutMptfDir(t *testing.T) {
+	stubTransformEnv(t, queryConfig, queryTerraformCode)
+	stub := gostub.Stub(&cf, &commonFlags{
+		tfDir:       "/testTerraform",
+		parallelism: 1,
+	})
+	defer stub.Reset()
+
+	out := new(bytes.Buffer)
+	err := query(false, `data.resource.all.result.azurerm_storage_account`, formatTable, context.Background(), out)
+	require.NoError(t, err)
+	assert.Equal(t, `QUERY       ADDRESS                                  LOCATION
+expression  resource.azurerm_storage_account.secure  main.tf:1-3
+expression  resource.azurerm_storage_account.legacy  main.tf:5-7
+`, out.String())
+}
+
+func TestQuery_ExpressionReplacesOutputs(t *testing.T) {
+	stubTransformEnv(t, queryConfig, queryTerraformCode)
+
+	out := new(bytes.Buffer)
+	err := query(false, `local.storage_accounts["secure"]`, formatTable, context.Background(), out)
+	require.NoError(t, err)
+	assert.Contains(t, out.String(), "resource.azurerm_storage_account.secure")
+	assert.NotContains(t, out.String(), "resource_group")
+}
+
+func TestQuery_NoMatches(t *testing.T) {
+	stubTransformEnv(t, queryConfig, queryTerraformCode)
+
+	out := new(bytes.Buffer)
+	err := query(false, `[]`, formatTable, context.Background(), out)
+	require.NoError(t, err)
+	assert.Equal(t, "No matches.\n", out.String())
+}
+
+func TestQuery_Errors(t *testing.T) {
+	cases := []struct {
+		desc       string
+		config     string
+		expression string
+		format     string
+		err        string
+	}{
+		{
+			desc:   "unknown format",
+			config: queryConfig,
+			format: "xml",
+			err:    `unknown format "xml"`,
+		},
+		{
+			desc: "transform in the query dir",
+			config: queryConfig + `
+transform "update_in_place" this {
+  target_block_address = "resource.azurerm_resource_group.this"
+}
+`,
+			format: formatTable,
+			err:    "query dirs cannot declare transforms",
+		},
+		{
+			desc:   "no output",
+			config: `locals {}`,
+			format: formatTable,
+			err:    "no `output` block found",
+		},
+		{
+			desc:       "value that isn't a block",
+			config:     queryConfig,
+			expression: `[1]`,
+			format:     formatTable,
+			err:        "value must be Terraform blocks or block addresses",
+		},
+		{
+			desc:       "unknown address",
+			config:     queryConfig,
+			expression: `"resource.azurerm_resource_group.missing"`,
+			format:     formatTable,
+			err:        "no block with address resource.azurerm_resource_group.missing",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stubTransformEnv(t, c.config, queryTerraformCode)
+			err := query(false, c.expression, c.format, context.Background(), new(bytes.Buffer))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.err)
+		})
+	}
+}
+
+func TestQuery_RecursiveLoadsQueryOnceForAllModules(t *testing.T) {
+	fs := stubTransformEnv(t, `data "resource" "storage" {
+  resource_type = "azurerm_storage_account"
+}
+
+output "no_min_tls_version" {
+  value = [for r in try(data.resource.storage.result.azurerm_storage_account, {}) : r if !contains(keys(r), "min_tls_version")]
+}
+`, queryTerraformCode)
+	require.NoError(t, afero.WriteFile(fs, "/testTerraform/storage/main.tf", []byte(`resource "azurerm_storage_account" "nested" {
+}
+`), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/testTerraform/.terraform/modules/modules.json", []byte(`{"Modules":[{"Key":"","Source":"","Dir":"/testTerraform"},{"Key":"storage","Source":"./storage","Dir":"/testTerraform/storage"}]}`), 0644))
+
+	out := new(bytes.Buffer)
+	require.NoError(t, query(true, "", formatTable, context.Background(), out))
+	assert.Equal(t, `QUERY               ADDRESS                                  LOCATION
+no_min_tls_version  resource.azurerm_storage_account.legacy  main.tf:5-7
+no_min_tls_version  resource.azurerm_storage_account.nested  storage/main.tf:1-2
+`, out.String())
+}
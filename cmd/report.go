@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/mapotf/pkg"
+	"github.com/spf13/afero"
+)
+
+// transformReport is the document `--report-json` writes: one entry per
+// module ref, listing what every applied transform did to that module.
+type transformReport struct {
+	Modules []moduleReport `json:"modules"`
+}
+
+type moduleReport struct {
+	Key        string                `json:"key"`
+	Dir        string                `json:"dir"`
+	Transforms []pkg.TransformResult `json:"transforms"`
+}
+
+func newTransformReport(moduleRefs []*pkg.TerraformModuleRef, applied []appliedPlan) transformReport {
+	r := transformReport{
+		Modules: []moduleReport{},
+	}
+	for _, ref := range moduleRefs {
+		mr := moduleReport{
+			Key:        ref.Key,
+			Dir:        ref.Dir,
+			Transforms: []pkg.TransformResult{},
+		}
+		for _, a := range applied {
+			if a.moduleRef != ref {
+				continue
+			}
+			mr.Transforms = append(mr.Transforms, a.plan.Results()...)
+		}
+		// Independent transforms have no fixed apply order, keep the report stable.
+		sort.SliceStable(mr.Transforms, func(i, j int) bool {
+			return mr.Transforms[i].Address < mr.Transforms[j].Address
+		})
+		r.Modules = append(r.Modules, mr)
+	}
+	return r
+}
+
+func writeTransformReport(fs afero.Fs, path string, moduleRefs []*pkg.TerraformModuleRef, applied []appliedPlan) error {
+	content, err := json.MarshalIndent(newTransformReport(moduleRefs, applied), "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal transform report: %+v", err)
+	}
+	if err = afero.WriteFile(fs, path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write transform report to %s: %+v", path, err)
+	}
+	return nil
+}
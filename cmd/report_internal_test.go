@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanTransform_WritesJsonReport(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+
+resource "fake_resource" that {
+  tags = {}
+}
+`
+	fs := stubTransformEnv(t, `
+data resource "fake_resource" {
+  resource_type = "fake_resource"
+}
+
+transform update_in_place "fake_resource" {
+ for_each = data.resource.fake_resource.result.fake_resource
+ target_block_address = each.value.mptf.block_address
+ asraw {
+   tags = {}
+ }
+}
+
+transform remove_block_element "nothing" {
+ target_block_address = "resource.fake_resource.that"
+ paths = ["lifecycle"]
+}
+`, terraformCode)
+
+	require.NoError(t, planTransform(false, context.Background(), "/report.json", false, io.Discard))
+
+	report, err := afero.ReadFile(fs, "/report.json")
+	require.NoError(t, err)
+	expected := `{
+  "modules": [
+    {
+      "key": "",
+      "dir": "/testTerraform",
+      "transforms": [
+        {
+          "address": "transform.remove_block_element.nothing",
+          "type": "remove_block_element",
+          "target_block_addresses": [],
+          "changed_files": [],
+          "no_op": true
+        },
+        {
+          "address": "transform.update_in_place.fake_resource[that]",
+          "type": "update_in_place",
+          "target_block_addresses": [],
+          "changed_files": [],
+          "no_op": true
+        },
+        {
+          "address": "transform.update_in_place.fake_resource[this]",
+          "type": "update_in_place",
+          "target_block_addresses": [
+            "resource.fake_resource.this"
+          ],
+          "changed_files": [
+            "main.tf"
+          ],
+          "no_op": false
+        }
+      ]
+    }
+  ]
+}
+`
+	assert.Equal(t, expected, string(report))
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, terraformCode, string(tfFile))
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/Azure/mapotf/pkg"
 	"github.com/Azure/mapotf/pkg/backup"
@@ -9,16 +10,22 @@ import (
 )
 
 func NewResetCmd() *cobra.Command {
-	return &cobra.Command{
+	var transforms []string
+	resetCmd := &cobra.Command{
 		Use:   "reset",
-		Short: "Reset all transformed Terraform files, mapotf reset --tf-dir",
+		Short: "Reset all transformed Terraform files, or only the changes of the given transforms, mapotf reset [--transform address] --tf-dir",
 		FParseErrWhitelist: cobra.FParseErrWhitelist{
 			UnknownFlags: true,
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(transforms) > 0 {
+				return resetTransforms(transforms)
+			}
 			return reset()
 		},
 	}
+	resetCmd.Flags().StringSliceVar(&transforms, "transform", nil, "Revert only the changes recorded for this transform, like `transform.update_in_place.tags`. Without a `[key]` suffix every instance of the transform is reverted. Can be repeated.")
+	return resetCmd
 }
 
 func reset() error {
@@ -37,6 +44,43 @@ func reset() error {
 	return nil
 }
 
+// resetTransforms reverts the journaled changes of the given transforms in
+// every module, leaving the changes of every other transform in place. Every
+// module is reverted in memory first, so nothing is written when any change
+// has drifted or any address has no recorded change.
+func resetTransforms(addresses []string) error {
+	moduleRefs, err := pkg.ModuleRefs(cf.tfDir)
+	if err != nil {
+		return err
+	}
+	var reverts []*backup.Revert
+	reverted := make(map[string]int)
+	for _, tfDir := range moduleRefs {
+		r, err := backup.PlanRevert(tfDir.AbsDir, addresses)
+		if err != nil {
+			return fmt.Errorf("cannot reset %s in %s: %+v", strings.Join(addresses, ", "), tfDir.Dir, err)
+		}
+		for address, n := range r.Reverted {
+			reverted[address] += n
+		}
+		reverts = append(reverts, r)
+	}
+	for _, address := range addresses {
+		if reverted[address] == 0 {
+			return fmt.Errorf("no changes recorded for %s", address)
+		}
+	}
+	for _, r := range reverts {
+		if err = r.Write(); err != nil {
+			return err
+		}
+	}
+	for _, address := range addresses {
+		fmt.Printf("%s has been reverted.\n", address)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(NewResetCmd())
 }
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg/backup"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const resetTestMptfConfig = `
+data resource "fake_resource" {
+  resource_type = "fake_resource"
+}
+
+transform update_in_place "tags" {
+  for_each             = data.resource.fake_resource.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  asraw {
+    tags = {}
+  }
+}
+
+transform new_block "locals" {
+  new_block_type = "locals"
+  filename       = "locals.tf"
+  asraw {
+    env = "dev"
+  }
+}
+`
+
+func TestResetTransforms_RevertsOnlyTheGivenTransform(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+
+resource "fake_resource" that {
+  name = "that"
+}
+`
+	fs := stubTransformEnv(t, resetTestMptfConfig, terraformCode)
+	_, err := transform(false, context.Background(), "", false)
+	require.NoError(t, err)
+	journal, err := backup.ReadJournal("/testTerraform")
+	require.NoError(t, err)
+	require.Len(t, journal.Changesets, 3)
+
+	require.NoError(t, resetTransforms([]string{"transform.update_in_place.tags"}))
+
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, terraformCode, string(tfFile))
+	locals, err := afero.ReadFile(fs, "/testTerraform/locals.tf")
+	require.NoError(t, err)
+	assert.Contains(t, string(locals), `env = "dev"`)
+	journal, err = backup.ReadJournal("/testTerraform")
+	require.NoError(t, err)
+	require.Len(t, journal.Changesets, 1)
+	assert.Equal(t, "transform.new_block.locals", journal.Changesets[0].Transform)
+
+	require.NoError(t, resetTransforms([]string{"transform.new_block.locals"}))
+	exists, err := afero.Exists(fs, "/testTerraform/locals.tf")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = afero.Exists(fs, filepath.Join("/testTerraform", backup.JournalDir))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestResetTransforms_FailsWhenCodeHasDrifted(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	fs := stubTransformEnv(t, resetTestMptfConfig, terraformCode)
+	_, err := transform(false, context.Background(), "", false)
+	require.NoError(t, err)
+	drifted := `resource "fake_resource" this {
+  tags = {
+    owner = "me"
+  }
+}
+`
+	require.NoError(t, afero.WriteFile(fs, "/testTerraform/main.tf", []byte(drifted), 0644))
+
+	err = resetTransforms([]string{"transform.update_in_place.tags[this]"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "main.tf has drifted")
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, drifted, string(tfFile))
+}
+
+func TestResetTransforms_WritesNothingWhenALaterTransformHasDrifted(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	fs := stubTransformEnv(t, resetTestMptfConfig, terraformCode)
+	_, err := transform(false, context.Background(), "", false)
+	require.NoError(t, err)
+	transformed, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	drifted := `locals {
+  env = "prod"
+}
+`
+	require.NoError(t, afero.WriteFile(fs, "/testTerraform/locals.tf", []byte(drifted), 0644))
+
+	err = resetTransforms([]string{"transform.update_in_place.tags", "transform.new_block.locals"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "locals.tf has drifted")
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, string(transformed), string(tfFile))
+	journal, err := backup.ReadJournal("/testTerraform")
+	require.NoError(t, err)
+	assert.Len(t, journal.Changesets, 2)
+}
+
+func TestResetTransforms_UnknownTransformShouldFail(t *testing.T) {
+	stubTransformEnv(t, resetTestMptfConfig, `resource "fake_resource" this {
+}
+`)
+	err := resetTransforms([]string{"transform.update_in_place.unknown"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no changes recorded for transform.update_in_place.unknown")
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Azure/mapotf/pkg/sarif"
+	"github.com/go-git/go-git/v5"
+	"github.com/hashicorp/hcl/v2"
+)
+
+// Output formats of `query` and `lint`.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatSarif = "sarif"
+)
+
+// located is the Terraform block a result of `query` or `lint` points at.
+// Lines and columns come from the range of the block and File is relative to
+// `--tf-dir`, while path is the file as the module ref locates it.
+type located struct {
+	Address     string `json:"address"`
+	ModuleKey   string `json:"module_key"`
+	File        string `json:"file"`
+	StartLine   int    `json:"start_line"`
+	StartColumn int    `json:"start_column"`
+	EndLine     int    `json:"end_line"`
+	EndColumn   int    `json:"end_column"`
+	path        string
+}
+
+// locatedResult is a result of `query` or `lint`, written by writeResults.
+type locatedResult interface {
+	comparable
+	location() located
+	// ruleID names the query or rule the result belongs to.
+	ruleID() string
+	// tableRow is the tab separated row of the result in the table format.
+	tableRow() string
+	// sarifResult is the result in a SARIF log, root is the repository root
+	// found by repoRoot.
+	sarifResult(root string) sarif.Result
+}
+
+func newLocated(address, moduleKey, dir string, rng hcl.Range) located {
+	path := filepath.Join(dir, rng.Filename)
+	return located{
+		Address:     address,
+		ModuleKey:   moduleKey,
+		File:        displayPath(path),
+		StartLine:   rng.Start.Line,
+		StartColumn: rng.Start.Column,
+		EndLine:     rng.End.Line,
+		EndColumn:   rng.End.Column,
+		path:        path,
+	}
+}
+
+// lines is the location of the block in the table format.
+func (l located) lines() string {
+	return fmt.Sprintf("%s:%d-%d", l.File, l.StartLine, l.EndLine)
+}
+
+func (l located) sarifLocation(root string) sarif.Location {
+	return sarif.NewLocation(repoPath(root, l.path), sarif.Region{
+		StartLine:   l.StartLine,
+		StartColumn: l.StartColumn,
+		EndLine:     l.EndLine,
+		EndColumn:   l.EndColumn,
+	})
+}
+
+func checkFormat(format string) error {
+	if format != formatTable && format != formatJSON && format != formatSarif {
+		return fmt.Errorf("unknown format %q, must be one of `%s`, `%s` or `%s`", format, formatTable, formatJSON, formatSarif)
+	}
+	return nil
+}
+
+// sortResults sorts results by location and drops duplicates, like a block
+// listed twice in one output or the same rule declared in two mptf dirs.
+func sortResults[T locatedResult](results []T) []T {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i].location(), results[j].location()
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		return results[i].ruleID() < results[j].ruleID()
+	})
+	r := []T{}
+	seen := make(map[T]struct{})
+	for _, result := range results {
+		if _, ok := seen[result]; ok {
+			continue
+		}
+		seen[result] = struct{}{}
+		r = append(r, result)
+	}
+	return r
+}
+
+// writeResults writes results to out in format. The table starts with header
+// and reads empty without results, the SARIF log declares rules.
+func writeResults[T locatedResult](out io.Writer, format string, results []T, header, empty string, rules []sarif.Rule) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case formatSarif:
+		root := repoRoot(cf.tfDir)
+		var sarifResults []sarif.Result
+		for _, result := range results {
+			sarifResults = append(sarifResults, result.sarifResult(root))
+		}
+		return sarif.NewLog(sarif.Driver{
+			Name:           "mapotf",
+			Version:        version,
+			InformationURI: "https://github.com/Azure/mapotf",
+			Rules:          rules,
+		}, sarifResults).Write(out)
+	}
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(out, empty)
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, header)
+	for _, result := range results {
+		_, _ = fmt.Fprintln(w, result.tableRow())
+	}
+	return w.Flush()
+}
+
+// repoRoot is the root of the git repository holding tfDir, with symlinks
+// resolved, or empty outside of a git repository.
+func repoRoot(tfDir string) string {
+	repo, err := git.PlainOpenWithOptions(tfDir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return ""
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return ""
+	}
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return ""
+	}
+	return root
+}
+
+// repoPath renders path relative to the repository root, as code scanning
+// services expect SARIF locations, or like displayPath without a root.
+func repoPath(root, path string) string {
+	if root == "" {
+		return displayPath(path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return displayPath(path)
+	}
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = realPath
+	}
+	rel, err := filepath.Rel(root, absPath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return displayPath(path)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortResults(t *testing.T) {
+	legacy := located{Address: "resource.fake_resource.legacy", File: "main.tf", StartLine: 5, EndLine: 7}
+	this := located{Address: "resource.fake_resource.this", File: "main.tf", StartLine: 1, EndLine: 3}
+	nested := located{Address: "resource.fake_resource.nested", File: "modules/main.tf", StartLine: 1, EndLine: 2}
+	results := []queryMatch{
+		{Query: "b", located: nested},
+		{Query: "b", located: legacy},
+		{Query: "a", located: legacy},
+		{Query: "b", located: this},
+		{Query: "b", located: legacy},
+	}
+	assert.Equal(t, []queryMatch{
+		{Query: "b", located: this},
+		{Query: "a", located: legacy},
+		{Query: "b", located: legacy},
+		{Query: "b", located: nested},
+	}, sortResults(results))
+}
+
+func TestWriteResults_JSONFlattensLocation(t *testing.T) {
+	out := new(bytes.Buffer)
+	require.NoError(t, writeResults(out, formatJSON, []lintFinding{
+		{
+			Rule:     "min_tls_version",
+			Severity: "error",
+			Message:  "found",
+			located: located{
+				Address:     "resource.fake_resource.this",
+				ModuleKey:   "mod",
+				File:        "main.tf",
+				StartLine:   1,
+				StartColumn: 1,
+				EndLine:     3,
+				EndColumn:   2,
+				path:        "/main.tf",
+			},
+		},
+	}, "", "", nil))
+	assert.Equal(t, `[
+  {
+    "rule": "min_tls_version",
+    "severity": "error",
+    "message": "found",
+    "address": "resource.fake_resource.this",
+    "module_key": "mod",
+    "file": "main.tf",
+    "start_line": 1,
+    "start_column": 1,
+    "end_line": 3,
+    "end_column": 2
+  }
+]
+`, out.String())
+}
+
+func TestRepoPath(t *testing.T) {
+	repoDir := t.TempDir()
+	_, err := git.PlainInit(repoDir, false)
+	require.NoError(t, err)
+	tfDir := filepath.Join(repoDir, "infra", "prod")
+	require.NoError(t, os.MkdirAll(filepath.Join(tfDir, "modules"), 0755))
+
+	root := repoRoot(tfDir)
+	assert.Equal(t, "infra/prod/main.tf", repoPath(root, filepath.Join(tfDir, "main.tf")))
+	assert.Equal(t, "infra/prod/modules/main.tf", repoPath(root, filepath.Join(tfDir, "modules", "main.tf")))
+}
+
+func TestRepoPath_OutsideGitRepository(t *testing.T) {
+	tfDir := t.TempDir()
+	stub := gostub.Stub(&cf, &commonFlags{tfDir: tfDir})
+	defer stub.Reset()
+
+	assert.Empty(t, repoRoot(tfDir))
+	assert.Equal(t, "main.tf", repoPath("", filepath.Join(tfDir, "main.tf")))
+}
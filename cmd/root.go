@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/Azure/mapotf/pkg"
+	"github.com/Azure/mapotf/pkg/backup"
 	"github.com/spf13/cobra"
 	"os"
 	"os/exec"
@@ -30,6 +32,9 @@ pipelines.`,
 	},
 	SilenceErrors: false,
 	SilenceUsage:  true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		cf.terraformOptions = newTerraformOptions(cmd)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -54,5 +59,10 @@ func init() {
 	rootCmd.PersistentFlags().StringSliceVar(&cf.mptfDirs, "mptf-dir", nil, "MPTF directory")
 
 	rootCmd.PersistentFlags().StringSlice("mptf-var", cf.mptfVars, "Set a value for one of the input variables in the root module of the configuration. Use this option more than once to set more than one variable.")
+	rootCmd.PersistentFlags().BoolVar(&cf.openTofu, "opentofu", false, "Run in OpenTofu mode: load `.tofu` and `.tofu.json` files, which take precedence over `.tf` files with the same name, and use the `tofu` binary for wrapped commands, provider schemas and module downloads. Auto-detected when not set: enabled if the Terraform directory contains `.tofu` files, or `tofu` is on PATH and `terraform` is not.")
+	rootCmd.PersistentFlags().BoolVar(&cf.includeOverrides, "include-overrides", false, "Load override files (`override.tf`, `*_override.tf`) and merge them into the blocks they override, so `data` blocks see the configuration Terraform evaluates")
+	rootCmd.PersistentFlags().StringVar(&cf.backupStrategy, "backup-strategy", backup.StrategyFile, "How transformed modules are backed up: `file` writes `.mptfbackup` copies next to the Terraform files, `git` records the HEAD commit and restores touched files from it (the module must be a clean git worktree), `auto` uses `git` when possible and `file` otherwise")
+	rootCmd.PersistentFlags().StringVar(&cf.conflictMode, "conflict-mode", pkg.ConflictModeWarn, "What to do when a transform overwrites an attribute another transform wrote without listing it in `depends_on`: `warn` prints a warning, `error` fails the transform before anything is written, `off` doesn't check. Only transforms of the same phase of the same mptf dir are checked against each other, since phases and mptf dirs run in a fixed order")
+	rootCmd.PersistentFlags().IntVar(&cf.parallelism, "parallelism", 1, "Number of module refs transformed concurrently in recursive mode. The output and errors are still reported in module order.")
 	rootCmd.PersistentFlags().StringSlice("mptf-var-file", cf.mptfVarFiles, "Load variable values from the given file, in addition to the default files mptf.mptfvars and *.auto.mptfvars. Use this option more than once to include more than one variables file.")
 }
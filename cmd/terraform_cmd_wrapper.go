@@ -8,13 +8,15 @@ import (
 
 func wrapTerraformCommandWithEphemeralTransform(tfDir, tfCmd string, recursive *bool) func(*cobra.Command, []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		restores, err := transform(*recursive, cmd.Context())
+		restores, err := transform(*recursive, cmd.Context(), "", false)
 		if err != nil {
 			return err
 		}
 		for _, restore := range restores {
 			r := restore
-			defer r()
+			defer func() {
+				_ = r()
+			}()
 		}
 		return wrapTerraformCommand(tfDir, tfCmd)(cmd, args)
 	}
@@ -23,7 +25,7 @@ func wrapTerraformCommandWithEphemeralTransform(tfDir, tfCmd string, recursive *
 func wrapTerraformCommand(tfDir, cmd string) func(*cobra.Command, []string) error {
 	return func(c *cobra.Command, args []string) error {
 		tfArgs := append([]string{cmd}, NonMptfArgs...)
-		tfCmd := exec.CommandContext(c.Context(), "terraform", tfArgs...)
+		tfCmd := exec.CommandContext(c.Context(), cf.terraformOptions.BinaryName(), tfArgs...)
 		tfCmd.Dir = tfDir
 		tfCmd.Stdin = os.Stdin
 		tfCmd.Stdout = os.Stdout
@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const mptfTestFileSuffix = ".mptftest.hcl"
+
+// mptfTestFile is the content of one `*.mptftest.hcl` file.
+type mptfTestFile struct {
+	Tests []*mptfTestCase `hcl:"test,block"`
+}
+
+// mptfTestCase applies the transforms of the mptf dir under test to a copy of
+// InputDir and compares the result with ExpectedDir and ExpectedBlocks. Asserts
+// are checked against the plan of InputDir, before anything is applied. Paths
+// are relative to the test file.
+type mptfTestCase struct {
+	Name           string               `hcl:"name,label"`
+	InputDir       string               `hcl:"input_dir"`
+	ExpectedDir    string               `hcl:"expected_dir,optional"`
+	Variables      cty.Value            `hcl:"variables,optional"`
+	Asserts        []*mptfAssert        `hcl:"assert,block"`
+	ExpectedBlocks []*mptfExpectedBlock `hcl:"expected_block,block"`
+}
+
+// mptfAssert is a condition evaluated like an expression in `mapotf debug`,
+// so it can reference `data`, `transform`, `var` and `local` values.
+type mptfAssert struct {
+	Condition    hcl.Expression `hcl:"condition"`
+	ErrorMessage hcl.Expression `hcl:"error_message,optional"`
+}
+
+// mptfExpectedBlock is the expected rendering of one root block, compared
+// after formatting both sides.
+type mptfExpectedBlock struct {
+	Address string `hcl:"address,label"`
+	Content string `hcl:"content"`
+}
+
+func NewTestCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "test-rules",
+		Short: "Run the `*.mptftest.hcl` tests found in the mptf dirs against their golden fixtures, mapotf test-rules --mptf-dir [path to config files], support mutilple mptf dirs",
+		FParseErrWhitelist: cobra.FParseErrWhitelist{
+			UnknownFlags: true,
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMptfTests(cmd.Context(), cmd.OutOrStdout())
+		},
+	}
+}
+
+// runMptfTests runs every test declared in the `*.mptftest.hcl` files under
+// each mptf dir, or the current directory when no mptf dir is set. Every test
+// runs in its own in-memory sandbox, so fixtures are never modified.
+func runMptfTests(ctx context.Context, out io.Writer) error {
+	mptfDirs, dispose, err := localizeMptfDirs(ctx)
+	defer dispose()
+	if err != nil {
+		return err
+	}
+	if len(mptfDirs) == 0 {
+		mptfDirs = []string{"."}
+	}
+	total, failed := 0, 0
+	for _, mptfDir := range mptfDirs {
+		testFiles, err := findMptfTestFiles(mptfDir)
+		if err != nil {
+			return err
+		}
+		for _, testFile := range testFiles {
+			tests, err := loadMptfTestFile(testFile)
+			if err != nil {
+				return err
+			}
+			name := testFile
+			if rel, err := filepath.Rel(mptfDir, testFile); err == nil {
+				name = filepath.ToSlash(rel)
+			}
+			for _, tc := range tests {
+				total++
+				failures, err := runMptfTest(ctx, mptfDir, filepath.Dir(testFile), tc)
+				if err != nil {
+					failures = append(failures, err.Error())
+				}
+				if len(failures) == 0 {
+					_, _ = fmt.Fprintf(out, "PASS %s (%s)\n", tc.Name, name)
+					continue
+				}
+				failed++
+				_, _ = fmt.Fprintf(out, "FAIL %s (%s)\n", tc.Name, name)
+				for _, f := range failures {
+					_, _ = fmt.Fprintln(out, indent(strings.TrimRight(f, "\n"), "    "))
+				}
+			}
+		}
+	}
+	if total == 0 {
+		_, _ = fmt.Fprintf(out, "No `*%s` file found.\n", mptfTestFileSuffix)
+		return nil
+	}
+	_, _ = fmt.Fprintf(out, "%d passed, %d failed.\n", total-failed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d test(s) failed", failed, total)
+	}
+	return nil
+}
+
+func findMptfTestFiles(dir string) ([]string, error) {
+	var files []string
+	err := afero.Walk(filesystem.Fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && (info.Name() == ".terraform" || info.Name() == ".git") {
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasSuffix(info.Name(), mptfTestFileSuffix) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot search %s for `*%s` files: %+v", dir, mptfTestFileSuffix, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func loadMptfTestFile(path string) ([]*mptfTestCase, error) {
+	content, err := afero.ReadFile(filesystem.Fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %+v", path, err)
+	}
+	file, diag := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+	if diag.HasErrors() {
+		return nil, diag
+	}
+	var f mptfTestFile
+	if diag = gohcl.DecodeBody(file.Body, nil, &f); diag.HasErrors() {
+		return nil, diag
+	}
+	names := make(map[string]struct{})
+	for _, tc := range f.Tests {
+		if _, ok := names[tc.Name]; ok {
+			return nil, fmt.Errorf("%s: duplicate test %q", path, tc.Name)
+		}
+		names[tc.Name] = struct{}{}
+		if !tc.Variables.IsNull() && !tc.Variables.Type().IsObjectType() && !tc.Variables.Type().IsMapType() {
+			return nil, fmt.Errorf("%s: `variables` of test %q must be an object", path, tc.Name)
+		}
+	}
+	return f.Tests, nil
+}
+
+// runMptfTest returns a description of every mismatch between the transformed
+// module and the expectations of tc.
+func runMptfTest(ctx context.Context, mptfDir, testDir string, tc *mptfTestCase) ([]string, error) {
+	sandbox := filesystem.NewSandbox(filesystem.Fs)
+	realFs := filesystem.Fs
+	filesystem.Fs = sandbox
+	defer func() {
+		filesystem.Fs = realFs
+	}()
+	moduleRef, err := pkg.NewTerraformRootModuleRef(filepath.Join(testDir, tc.InputDir))
+	if err != nil {
+		return nil, err
+	}
+	var failures []string
+	if len(tc.Asserts) > 0 {
+		f, err := evaluateAsserts(ctx, moduleRef, mptfDir, tc.varFlags(), tc.Asserts)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, f...)
+	}
+	if _, err = applyTransform(moduleRef, mptfDir, tc.varFlags(), ctx, io.Discard); err != nil {
+		return nil, err
+	}
+	if tc.ExpectedDir != "" {
+		f, err := compareWithExpectedDir(sandbox, moduleRef.AbsDir, filepath.Join(testDir, tc.ExpectedDir))
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, f...)
+	}
+	if len(tc.ExpectedBlocks) == 0 {
+		return failures, nil
+	}
+	module, err := terraform.LoadModule(terraform.ModuleRef{
+		Dir:    moduleRef.Dir,
+		AbsDir: moduleRef.AbsDir,
+	}, cf.terraformOptions.LoadOptions)
+	if err != nil {
+		return nil, err
+	}
+	blocks := module.Snapshot().Blocks
+	for _, eb := range tc.ExpectedBlocks {
+		actual, ok := blocks[eb.Address]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("expected block %s not found", eb.Address))
+			continue
+		}
+		expected, got := formatBlock(eb.Content), formatBlock(actual)
+		if expected == got {
+			continue
+		}
+		diff, err := filesystem.FileChange{Before: []byte(expected), After: []byte(got)}.UnifiedDiff(eb.Address)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, fmt.Sprintf("block %s does not match the expected block:\n%s", eb.Address, diff))
+	}
+	return failures, nil
+}
+
+// evaluateAsserts plans the transforms of mptfDir over moduleRef, as `mapotf
+// debug` does, and returns a failure for every assert whose condition isn't
+// true.
+func evaluateAsserts(ctx context.Context, moduleRef *pkg.TerraformModuleRef, mptfDir string, varFlags []golden.CliFlagAssignedVariables, asserts []*mptfAssert) ([]string, error) {
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, mptfDir)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := pkg.NewMetaProgrammingTFConfig(moduleRef, nil, hclBlocks, varFlags, cf.terraformOptions, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = pkg.RunMetaProgrammingTFPlan(cfg); err != nil {
+		return nil, err
+	}
+	evalCtx := cfg.EvalContext()
+	var failures []string
+	for _, a := range asserts {
+		r := a.Condition.Range().String()
+		value, diag := a.Condition.Value(evalCtx)
+		if diag.HasErrors() {
+			failures = append(failures, fmt.Sprintf("assert (%s): %s", r, diag.Error()))
+			continue
+		}
+		if value.IsNull() || !value.IsKnown() || !value.Type().Equals(cty.Bool) {
+			failures = append(failures, fmt.Sprintf("assert (%s): condition must be a known bool value", r))
+			continue
+		}
+		if value.True() {
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("assert (%s) failed: %s", r, a.errorMessage(evalCtx)))
+	}
+	return failures, nil
+}
+
+func (a *mptfAssert) errorMessage(evalCtx *hcl.EvalContext) string {
+	const defaultMessage = "condition is false"
+	if a.ErrorMessage == nil {
+		return defaultMessage
+	}
+	value, diag := a.ErrorMessage.Value(evalCtx)
+	if diag.HasErrors() {
+		return fmt.Sprintf("%s, and error_message cannot be evaluated: %s", defaultMessage, diag.Error())
+	}
+	if value.IsNull() || !value.IsKnown() || !value.Type().Equals(cty.String) {
+		return defaultMessage
+	}
+	return value.AsString()
+}
+
+func (tc *mptfTestCase) varFlags() []golden.CliFlagAssignedVariables {
+	if tc.Variables.IsNull() {
+		return nil
+	}
+	values := tc.Variables.AsValueMap()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var flags []golden.CliFlagAssignedVariables
+	for _, name := range names {
+		flags = append(flags, golden.NewCliFlagAssignedVariable(name, string(hclwrite.TokensForValue(values[name]).Bytes())))
+	}
+	return flags
+}
+
+// compareWithExpectedDir diffs every file of expectedDir with the file of the
+// same name in actualDir. A file the transforms changed or created in
+// actualDir but that expectedDir lacks is a mismatch too, while untouched
+// files may be left out of expectedDir.
+func compareWithExpectedDir(sandbox *filesystem.Sandbox, actualDir, expectedDir string) ([]string, error) {
+	entries, err := afero.ReadDir(sandbox, expectedDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read expected dir %s: %+v", expectedDir, err)
+	}
+	var failures []string
+	expected := make(map[string]struct{})
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		expected[e.Name()] = struct{}{}
+		want, err := afero.ReadFile(sandbox, filepath.Join(expectedDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		got, err := afero.ReadFile(sandbox, filepath.Join(actualDir, e.Name()))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if bytes.Equal(want, got) {
+			continue
+		}
+		diff, err := filesystem.FileChange{Before: want, After: got}.UnifiedDiff(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, fmt.Sprintf("%s does not match the expected file:\n%s", e.Name(), diff))
+	}
+	changes, err := terraformChanges(sandbox)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		name := filepath.Base(c.Path)
+		if _, ok := expected[name]; ok || filepath.Dir(c.Path) != filepath.Clean(actualDir) {
+			continue
+		}
+		diff, err := c.UnifiedDiff(name)
+		if err != nil {
+			return nil, err
+		}
+		failures = append(failures, fmt.Sprintf("%s was changed but is missing from the expected dir:\n%s", name, diff))
+	}
+	return failures, nil
+}
+
+func formatBlock(content string) string {
+	return strings.TrimSpace(string(hclwrite.Format([]byte(content))))
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+func init() {
+	rootCmd.AddCommand(NewTestCmd())
+}
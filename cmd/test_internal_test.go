@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `variable "owner" {
+  type = string
+}
+
+data "resource" fake_resource {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" fake_resource {
+  for_each             = data.resource.fake_resource.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  asstring {
+    tags = "{ owner = \"${var.owner}\" }"
+  }
+}
+`
+
+func stubTestEnv(t *testing.T, files map[string]string) afero.Fs {
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/rules/main.mptf.hcl", []byte(testRules), 0644))
+	for path, content := range files {
+		require.NoError(t, afero.WriteFile(fs, path, []byte(content), 0644))
+	}
+	stub := gostub.Stub(&filesystem.Fs, fs).
+		Stub(&os.Args, []string{"mapotf", "test-rules"}).
+		Stub(&cf, &commonFlags{
+			mptfDirs:    []string{"/rules"},
+			parallelism: 1,
+		}).
+		Stub(&pkg.AbsDir, func(dir string) (string, error) {
+			return dir, nil
+		})
+	t.Cleanup(stub.Reset)
+	return fs
+}
+
+func TestRunMptfTests_PassingAndFailingTests(t *testing.T) {
+	input := `resource "fake_resource" "this" {
+}
+`
+	fs := stubTestEnv(t, map[string]string{
+		"/rules/tests/fixtures/input/main.tf": input,
+		"/rules/tests/fixtures/expected/main.tf": `resource "fake_resource" "this" {
+  tags = { owner = "platform" }
+}
+`,
+		"/rules/tests/tags.mptftest.hcl": `test "golden_dir" {
+  input_dir    = "fixtures/input"
+  expected_dir = "fixtures/expected"
+  variables = {
+    owner = "platform"
+  }
+}
+
+test "inline_block" {
+  input_dir = "fixtures/input"
+  variables = {
+    owner = "platform"
+  }
+  expected_block "resource.fake_resource.this" {
+    content = <<-EOT
+      resource "fake_resource" "this" {
+        tags = { owner = "platform" }
+      }
+    EOT
+  }
+}
+
+test "wrong_owner" {
+  input_dir    = "fixtures/input"
+  expected_dir = "fixtures/expected"
+  variables = {
+    owner = "security"
+  }
+}
+`,
+	})
+
+	out := new(bytes.Buffer)
+	err := runMptfTests(context.Background(), out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "1 of 3 test(s) failed")
+	output := out.String()
+	assert.Contains(t, output, "PASS golden_dir (tests/tags.mptftest.hcl)")
+	assert.Contains(t, output, "PASS inline_block (tests/tags.mptftest.hcl)")
+	assert.Contains(t, output, "FAIL wrong_owner (tests/tags.mptftest.hcl)")
+	assert.Contains(t, output, "main.tf does not match the expected file:")
+	assert.Contains(t, output, `-  tags = { owner = "platform" }`)
+	assert.Contains(t, output, `+  tags = { owner = "security" }`)
+	assert.Contains(t, output, "2 passed, 1 failed.")
+	content, err := afero.ReadFile(fs, "/rules/tests/fixtures/input/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, input, string(content))
+}
+
+func TestRunMptfTests_UnexpectedChangeAndMissingBlock(t *testing.T) {
+	stubTestEnv(t, map[string]string{
+		"/rules/tests/input/main.tf": `resource "fake_resource" "this" {
+}
+`,
+		"/rules/tests/input/other.tf": `resource "fake_resource" "that" {
+}
+`,
+		"/rules/tests/expected/main.tf": `resource "fake_resource" "this" {
+  tags = { owner = "platform" }
+}
+`,
+		"/rules/tests/main.mptftest.hcl": `test "untracked_file" {
+  input_dir    = "input"
+  expected_dir = "expected"
+  variables = {
+    owner = "platform"
+  }
+  expected_block "resource.fake_resource.missing" {
+    content = ""
+  }
+}
+`,
+	})
+
+	out := new(bytes.Buffer)
+	err := runMptfTests(context.Background(), out)
+	require.Error(t, err)
+	assert.Contains(t, out.String(), "other.tf was changed but is missing from the expected dir:")
+	assert.Contains(t, out.String(), "expected block resource.fake_resource.missing not found")
+	assert.NotContains(t, out.String(), "main.tf does not match")
+}
+
+func TestRunMptfTests_DuplicateTestNameShouldFail(t *testing.T) {
+	stubTestEnv(t, map[string]string{
+		"/rules/dup.mptftest.hcl": `test "a" {
+  input_dir = "input"
+}
+
+test "a" {
+  input_dir = "input"
+}
+`,
+	})
+
+	err := runMptfTests(context.Background(), new(bytes.Buffer))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `duplicate test "a"`)
+}
+
+func TestRunMptfTests_Asserts(t *testing.T) {
+	stubTestEnv(t, map[string]string{
+		"/rules/tests/input/main.tf": `resource "fake_resource" "this" {
+}
+
+resource "fake_resource" "that" {
+}
+`,
+		"/rules/tests/asserts.mptftest.hcl": `test "asserts" {
+  input_dir = "input"
+  variables = {
+    owner = "platform"
+  }
+  assert {
+    condition = length(data.resource.fake_resource.result.fake_resource) == 2
+  }
+  assert {
+    condition = sort([for t in transform.update_in_place.fake_resource : t.target_block_address]) == sort(["resource.fake_resource.this", "resource.fake_resource.that"])
+  }
+  assert {
+    condition     = length(data.resource.fake_resource.result.fake_resource) == 1
+    error_message = "found ${length(data.resource.fake_resource.result.fake_resource)} fake resources"
+  }
+  assert {
+    condition = "yes"
+  }
+}
+`,
+	})
+
+	out := new(bytes.Buffer)
+	err := runMptfTests(context.Background(), out)
+	require.Error(t, err)
+	output := out.String()
+	assert.Contains(t, output, "FAIL asserts (tests/asserts.mptftest.hcl)")
+	assert.Contains(t, output, "assert (/rules/tests/asserts.mptftest.hcl:13,21-82) failed: found 2 fake resources")
+	assert.Contains(t, output, "assert (/rules/tests/asserts.mptftest.hcl:17,17-22): condition must be a known bool value")
+	assert.Equal(t, 2, strings.Count(output, "assert ("))
+}
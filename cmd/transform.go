@@ -1,23 +1,34 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/Azure/golden"
 	"github.com/Azure/mapotf/pkg"
 	"github.com/Azure/mapotf/pkg/backup"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
 	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 )
 
 func NewTransformCmd() *cobra.Command {
 	recursive := false
 	keepAdjacentBlocks := false
+	dryRun := false
+	reportPath := ""
+	verify := false
 
 	transformCmd := &cobra.Command{
 		Use:   "transform",
-		Short: "Apply the transforms, mapotf transform [-r] [--keep-adjacent-blocks] --tf-dir [] --mptf-dir  [path to config files], support mutilple mptf dirs",
+		Short: "Apply the transforms, mapotf transform [-r] [--plan] [--verify-idempotent] [--keep-adjacent-blocks] [--report-json file] --tf-dir [] --mptf-dir  [path to config files], support mutilple mptf dirs",
 		FParseErrWhitelist: cobra.FParseErrWhitelist{
 			UnknownFlags: true,
 		},
@@ -25,92 +36,449 @@ func NewTransformCmd() *cobra.Command {
 			terraform.SetNormalizeOptions(terraform.NormalizeOptions{
 				KeepAdjacentSameKindUnlabeledBlocks: keepAdjacentBlocks,
 			})
-			_, err := transform(recursive, cmd.Context())
+			if dryRun {
+				return planTransform(recursive, cmd.Context(), reportPath, verify, cmd.OutOrStdout())
+			}
+			_, err := transform(recursive, cmd.Context(), reportPath, verify)
 			return err
 		},
 	}
 
 	transformCmd.Flags().BoolVarP(&recursive, "recursive", "r", false, "Apply transforms to all modules or not, default to the root module only.")
 	transformCmd.Flags().BoolVar(&keepAdjacentBlocks, "keep-adjacent-blocks", false, "Keep adjacent unlabeled same-kind root blocks (e.g. two `locals {}` or two `moved {}` blocks) on directly consecutive lines with no blank line between them. Labeled blocks such as `variable \"x\" {}` or `resource \"t\" \"n\" {}` are unaffected and always get a blank line between siblings. Off by default: every pair of root blocks is separated by exactly one blank line.")
+	transformCmd.Flags().BoolVar(&dryRun, "plan", false, "Dry run: apply the transforms in memory and print a unified diff per changed file instead of writing to disk. No backup files are created.")
+	transformCmd.Flags().BoolVar(&verify, "verify-idempotent", false, "Apply the transforms a second time in memory and fail, without writing anything, if the second pass would change any file.")
+	transformCmd.Flags().StringVar(&reportPath, "report-json", "", "Write a JSON report to this file listing, for every module, each applied transform's address and type, the block addresses and files it changed, and whether it was a no-op.")
 	return transformCmd
 }
 
-func transform(recursive bool, ctx context.Context) ([]func(), error) {
-	var restore []func()
+// transform applies every transform to every module ref in a sandbox first,
+// so a failure anywhere leaves the disk untouched. Only when all of them
+// succeed are the modules backed up and the changes written. When backing up
+// or writing fails, the modules already backed up are restored.
+func transform(recursive bool, ctx context.Context, reportPath string, verify bool) ([]func() error, error) {
 	varFlags, err := varFlags(os.Args)
 	if err != nil {
 		return nil, err
 	}
-	rootMod, err := pkg.NewTerraformRootModuleRef(cf.tfDir)
+	moduleRefs, err := loadModuleRefs(recursive)
 	if err != nil {
 		return nil, err
 	}
-	moduleRefs := []*pkg.TerraformModuleRef{
-		rootMod,
+	mptfDirs, dispose, err := localizeMptfDirs(ctx)
+	defer dispose()
+	if err != nil {
+		return nil, err
 	}
-	if recursive {
-		modulePaths, err := pkg.ModuleRefs(cf.tfDir)
-		if err != nil {
-			return nil, err
+	sandbox, applied, err := sandboxedApply(moduleRefs, mptfDirs, varFlags, ctx, os.Stdout)
+	if err != nil {
+		return nil, fmt.Errorf("no change has been written: %+v", err)
+	}
+	if verify {
+		if err = verifyIdempotent(sandbox, moduleRefs, mptfDirs, varFlags, ctx); err != nil {
+			return nil, fmt.Errorf("no change has been written: %+v", err)
 		}
-		moduleRefs = modulePaths
 	}
+	changes, err := terraformChanges(sandbox)
+	if err != nil {
+		return nil, fmt.Errorf("no change has been written: %+v", err)
+	}
+	var restore []func() error
 	for _, moduleRef := range moduleRefs {
 		d := moduleRef
-		err = backup.BackupFolder(d.AbsDir)
-		restore = append(restore, func() {
-			_ = backup.Reset(d.AbsDir)
-		})
-		if err != nil {
-			return restore, err
+		if err = backupModule(d.AbsDir, changedFiles(d, changes)); err != nil {
+			return nil, runRestores(restore, err)
 		}
+		restore = append(restore, func() error {
+			return backup.Reset(d.AbsDir)
+		})
 	}
-	var mptfDirs []string
-	for _, dir := range cf.mptfDirs {
-		localizedDir, dispose, err := localizeConfigFolder(dir, ctx)
-		if err != nil {
-			return restore, err
+	if err = sandbox.Commit(); err != nil {
+		return nil, runRestores(restore, err)
+	}
+	for _, moduleRef := range moduleRefs {
+		if err = backup.AdoptNewFiles(moduleRef.AbsDir); err != nil {
+			return nil, runRestores(restore, err)
 		}
-		if dispose != nil {
-			defer dispose()
+	}
+	for _, a := range applied {
+		if err = backup.AppendJournal(a.moduleRef.AbsDir, a.plan.Changesets()); err != nil {
+			return nil, runRestores(restore, err)
 		}
-		mptfDirs = append(mptfDirs, localizedDir)
 	}
-	for _, mptfDir := range mptfDirs {
-		for _, tfDir := range moduleRefs {
-			hclBlocks, err := pkg.LoadMPTFHclBlocks(false, mptfDir)
-			if err != nil {
-				return nil, err
-			}
-			err = applyTransform(tfDir, hclBlocks, varFlags, ctx)
-			if err != nil {
-				return nil, err
-			}
+	if reportPath != "" {
+		if err = writeTransformReport(filesystem.Fs, reportPath, moduleRefs, applied); err != nil {
+			return restore, err
 		}
 	}
 	fmt.Println("Transforms applied successfully.")
 	return restore, nil
 }
 
-func applyTransform(m *pkg.TerraformModuleRef, hclBlocks []*golden.HclBlock, varFlags []golden.CliFlagAssignedVariables, ctx context.Context) error {
-	cfg, err := pkg.NewMetaProgrammingTFConfig(m, &cf.tfDir, hclBlocks, varFlags, ctx)
+// runRestores restores every module backed up so far after err, returning err
+// together with the errors of the restores that failed.
+func runRestores(restore []func() error, err error) error {
+	for _, r := range restore {
+		if restoreErr := r(); restoreErr != nil {
+			err = multierror.Append(err, restoreErr)
+		}
+	}
+	return err
+}
+
+// planTransform runs the whole transform pipeline against a sandboxed
+// filesystem and writes a unified diff of every file that would change to
+// out. Nothing is written to disk except the optional JSON report.
+func planTransform(recursive bool, ctx context.Context, reportPath string, verify bool, out io.Writer) error {
+	changes, _, err := sandboxedTransform(recursive, ctx, reportPath, verify)
 	if err != nil {
 		return err
 	}
-	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(out, "No changes. The transforms would not modify any file.")
+		return nil
+	}
+	for _, c := range changes {
+		diff, err := c.UnifiedDiff(displayPath(c.Path))
+		if err != nil {
+			return fmt.Errorf("cannot render diff for %s: %+v", c.Path, err)
+		}
+		_, _ = fmt.Fprint(out, diff)
+	}
+	return nil
+}
+
+// sandboxedTransform applies every transform to every module ref in an
+// in-memory copy-on-write layer over filesystem.Fs and returns the Terraform
+// files whose content would change, along with the applied plans. The real
+// filesystem is left untouched, except for the JSON report when reportPath is
+// set. With verify, it fails unless a second pass over the result would change
+// nothing.
+func sandboxedTransform(recursive bool, ctx context.Context, reportPath string, verify bool) ([]filesystem.FileChange, []appliedPlan, error) {
+	varFlags, err := varFlags(os.Args)
+	if err != nil {
+		return nil, nil, err
+	}
+	moduleRefs, err := loadModuleRefs(recursive)
+	if err != nil {
+		return nil, nil, err
+	}
+	mptfDirs, dispose, err := localizeMptfDirs(ctx)
+	defer dispose()
+	if err != nil {
+		return nil, nil, err
+	}
+	sandbox, applied, err := sandboxedApply(moduleRefs, mptfDirs, varFlags, ctx, io.Discard)
+	if err != nil {
+		return nil, nil, err
+	}
+	if verify {
+		if err = verifyIdempotent(sandbox, moduleRefs, mptfDirs, varFlags, ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+	if reportPath != "" {
+		if err = writeTransformReport(filesystem.Fs, reportPath, moduleRefs, applied); err != nil {
+			return nil, nil, err
+		}
+	}
+	changes, err := terraformChanges(sandbox)
+	if err != nil {
+		return nil, nil, err
+	}
+	return changes, applied, nil
+}
+
+// terraformChanges returns the changes in sandbox, leaving out backup files
+// and new file indicators.
+func terraformChanges(sandbox *filesystem.Sandbox) ([]filesystem.FileChange, error) {
+	changes, err := sandbox.Changes()
+	if err != nil {
+		return nil, err
+	}
+	var r []filesystem.FileChange
+	for _, c := range changes {
+		if strings.HasSuffix(c.Path, backup.NewFileExtension) || strings.HasSuffix(c.Path, backup.BackupExtension) {
+			continue
+		}
+		r = append(r, c)
+	}
+	return r, nil
+}
+
+// verifyIdempotent applies the transforms again on top of sandbox, in a
+// sandbox of its own, and fails listing the files and transforms the second
+// pass changed.
+func verifyIdempotent(sandbox *filesystem.Sandbox, moduleRefs []*pkg.TerraformModuleRef, mptfDirs []string, varFlags []golden.CliFlagAssignedVariables, ctx context.Context) error {
+	realFs := filesystem.Fs
+	filesystem.Fs = sandbox
+	secondPass, applied, err := sandboxedApply(moduleRefs, mptfDirs, varFlags, ctx, io.Discard)
+	filesystem.Fs = realFs
+	if err != nil {
+		return fmt.Errorf("cannot apply the transforms a second time: %+v", err)
+	}
+	changes, err := terraformChanges(secondPass)
 	if err != nil {
 		return err
 	}
-	if len(plan.Transforms) == 0 {
-		fmt.Println("No transforms to apply.")
+	if len(changes) == 0 {
 		return nil
 	}
-	fmt.Println(plan.String())
-	err = plan.Apply()
+	sb := strings.Builder{}
+	sb.WriteString("transforms are not idempotent, a second pass would change:\n")
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "  %s\n", displayPath(c.Path))
+	}
+	sb.WriteString("changed by:\n")
+	for _, a := range applied {
+		idempotent := make(map[string]bool)
+		for _, t := range a.plan.Transforms {
+			idempotent[t.Address()] = t.Idempotent()
+		}
+		for _, r := range a.plan.Results() {
+			if r.NoOp || r.Skipped {
+				continue
+			}
+			fmt.Fprintf(&sb, "  %s", r.Address)
+			if !idempotent[r.Address] {
+				fmt.Fprintf(&sb, " (%s is not idempotent)", r.Type)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return errors.New(strings.TrimSuffix(sb.String(), "\n"))
+}
+
+// sandboxedApply runs applyTransforms with filesystem.Fs swapped for a
+// sandbox over it, and returns the sandbox holding every write.
+func sandboxedApply(moduleRefs []*pkg.TerraformModuleRef, mptfDirs []string, varFlags []golden.CliFlagAssignedVariables, ctx context.Context, out io.Writer) (*filesystem.Sandbox, []appliedPlan, error) {
+	sandbox := filesystem.NewSandbox(filesystem.Fs)
+	realFs := filesystem.Fs
+	filesystem.Fs = sandbox
+	defer func() {
+		filesystem.Fs = realFs
+	}()
+	applied, err := applyTransforms(moduleRefs, mptfDirs, varFlags, ctx, out)
 	if err != nil {
-		return fmt.Errorf("error applying plan: %s", err.Error())
+		return nil, nil, err
 	}
-	return nil
+	return sandbox, applied, nil
+}
+
+// backupModule backs dir up with the strategy picked by `--backup-strategy`.
+// A module already backed up with git keeps that strategy, which only
+// records changedFiles, the names of the files about to be changed.
+func backupModule(dir string, changedFiles []string) error {
+	strategy := cf.backupStrategy
+	if strategy == backup.StrategyAuto {
+		strategy = backup.StrategyFile
+		hasGitBackup, err := backup.HasGitBackup(dir)
+		if err != nil {
+			return err
+		}
+		if hasGitBackup || backup.IsCleanGitWorktree(dir) {
+			strategy = backup.StrategyGit
+		}
+	}
+	switch strategy {
+	case backup.StrategyFile, "":
+		return backup.BackupFolder(dir)
+	case backup.StrategyGit:
+		return backup.GitBackupFolder(dir, changedFiles)
+	}
+	return fmt.Errorf("unknown backup strategy %q, must be one of `%s`, `%s` or `%s`", cf.backupStrategy, backup.StrategyFile, backup.StrategyGit, backup.StrategyAuto)
+}
+
+// changedFiles lists the names of the existing files of moduleRef that
+// changes modify.
+func changedFiles(moduleRef *pkg.TerraformModuleRef, changes []filesystem.FileChange) []string {
+	var r []string
+	for _, c := range changes {
+		dir := filepath.Dir(c.Path)
+		if c.Before != nil && (dir == filepath.Clean(moduleRef.Dir) || dir == moduleRef.AbsDir) {
+			r = append(r, filepath.Base(c.Path))
+		}
+	}
+	return r
+}
+
+func loadModuleRefs(recursive bool) ([]*pkg.TerraformModuleRef, error) {
+	if recursive {
+		return pkg.ModuleRefs(cf.tfDir)
+	}
+	rootMod, err := pkg.NewTerraformRootModuleRef(cf.tfDir)
+	if err != nil {
+		return nil, err
+	}
+	return []*pkg.TerraformModuleRef{rootMod}, nil
+}
+
+// localizeMptfDirs downloads remote `--mptf-dir` sources into temp folders.
+// The returned dispose func removes those folders and is always safe to call.
+func localizeMptfDirs(ctx context.Context) ([]string, func(), error) {
+	var mptfDirs []string
+	var disposes []func()
+	dispose := func() {
+		for _, d := range disposes {
+			d()
+		}
+	}
+	for _, dir := range cf.mptfDirs {
+		localizedDir, d, err := localizeConfigFolder(dir, ctx)
+		if d != nil {
+			disposes = append(disposes, d)
+		}
+		if err != nil {
+			return nil, dispose, err
+		}
+		mptfDirs = append(mptfDirs, localizedDir)
+	}
+	return mptfDirs, dispose, nil
+}
+
+// appliedPlan records the plan one phase of one mptf dir produced for one
+// module ref.
+type appliedPlan struct {
+	moduleRef *pkg.TerraformModuleRef
+	mptfDir   string
+	plan      *pkg.MetaProgrammingTFPlan
+}
+
+// applyTransforms applies every mptf dir to every module ref. Module refs are
+// independent, so up to `--parallelism` of them are transformed at once, while
+// the mptf dirs of one module ref run in order since each builds on the
+// result of the previous one. Output is printed module by module in
+// moduleRefs order, and a failure does not stop the others, so the returned
+// error lists every failing pair.
+func applyTransforms(moduleRefs []*pkg.TerraformModuleRef, mptfDirs []string, varFlags []golden.CliFlagAssignedVariables, ctx context.Context, out io.Writer) ([]appliedPlan, error) {
+	if cf.parallelism < 1 {
+		return nil, fmt.Errorf("--parallelism must be at least 1, got %d", cf.parallelism)
+	}
+	if err := pkg.CheckConflictMode(cf.conflictMode); err != nil {
+		return nil, err
+	}
+	results := make([]*moduleTransformResult, len(moduleRefs))
+	semaphore := make(chan struct{}, cf.parallelism)
+	var wg sync.WaitGroup
+	for i, tfDir := range moduleRefs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			results[i] = applyModuleTransforms(tfDir, mptfDirs, varFlags, ctx)
+		}()
+	}
+	wg.Wait()
+
+	var errs error
+	for _, r := range results {
+		_, _ = out.Write(r.output.Bytes())
+		if r.err != nil {
+			errs = multierror.Append(errs, r.err.Errors...)
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	var applied []appliedPlan
+	for i := range mptfDirs {
+		for j, tfDir := range moduleRefs {
+			for _, plan := range results[j].plans[i] {
+				applied = append(applied, appliedPlan{
+					moduleRef: tfDir,
+					mptfDir:   mptfDirs[i],
+					plan:      plan,
+				})
+			}
+		}
+	}
+	return applied, nil
+}
+
+// moduleTransformResult holds what the mptf dirs did to one module ref: the
+// plans of each mptf dir, one per phase that had transforms to apply, and the
+// buffered output.
+type moduleTransformResult struct {
+	plans  [][]*pkg.MetaProgrammingTFPlan
+	output bytes.Buffer
+	err    *multierror.Error
+}
+
+func applyModuleTransforms(tfDir *pkg.TerraformModuleRef, mptfDirs []string, varFlags []golden.CliFlagAssignedVariables, ctx context.Context) *moduleTransformResult {
+	r := &moduleTransformResult{
+		plans: make([][]*pkg.MetaProgrammingTFPlan, len(mptfDirs)),
+	}
+	for i, mptfDir := range mptfDirs {
+		plans, err := applyTransform(tfDir, mptfDir, varFlags, ctx, &r.output)
+		if err != nil {
+			r.err = multierror.Append(r.err, fmt.Errorf("%s on module %s: %+v", mptfDir, tfDir.AbsDir, err))
+			continue
+		}
+		r.plans[i] = plans
+	}
+	return r
+}
+
+// applyTransform plans and applies the mptf blocks of mptfDir against one
+// module ref, one phase at a time. Each phase loads the module and runs the
+// data blocks again, so it sees what the previous phases changed. It returns
+// the plan of every phase that had transforms to apply.
+func applyTransform(m *pkg.TerraformModuleRef, mptfDir string, varFlags []golden.CliFlagAssignedVariables, ctx context.Context, out io.Writer) ([]*pkg.MetaProgrammingTFPlan, error) {
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, mptfDir)
+	if err != nil {
+		return nil, err
+	}
+	phases, err := pkg.TransformPhases(hclBlocks)
+	if err != nil {
+		return nil, err
+	}
+	var plans []*pkg.MetaProgrammingTFPlan
+	for i, phase := range phases {
+		if i > 0 {
+			// Golden blocks are bound to the config they were decoded in.
+			if hclBlocks, err = pkg.LoadMPTFHclBlocks(false, mptfDir); err != nil {
+				return nil, err
+			}
+		}
+		cfg, err := pkg.NewMetaProgrammingTFConfig(m, &cf.tfDir, hclBlocks, varFlags, cf.terraformOptions, ctx)
+		if err != nil {
+			return nil, err
+		}
+		plan, err := pkg.RunMetaProgrammingTFPhasePlan(cfg, phase)
+		if err != nil {
+			return nil, err
+		}
+		if len(plan.Transforms) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintln(out, plan.String())
+		if err = plan.SetConflictMode(cf.conflictMode); err != nil {
+			return nil, err
+		}
+		if err = plan.Apply(); err != nil {
+			return nil, fmt.Errorf("error applying plan of phase %q: %s", phase, err.Error())
+		}
+		for _, c := range plan.Conflicts() {
+			_, _ = fmt.Fprintf(out, "Warning: %s\n", c.String())
+		}
+		plans = append(plans, plan)
+	}
+	if len(plans) == 0 {
+		_, _ = fmt.Fprintln(out, "No transforms to apply.")
+	}
+	return plans, nil
+}
+
+// displayPath renders path relative to `--tf-dir` when it lives under it, so
+// diffs read like `git diff` output from the module root.
+func displayPath(path string) string {
+	if rel, err := filepath.Rel(cf.tfDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
 }
 
 func init() {
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg"
+	"github.com/Azure/mapotf/pkg/backup"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/hashicorp/go-multierror"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform_FailureLeavesEveryModuleUntouched(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	fs := stubTransformEnv(t, checkTestMptfConfig, terraformCode)
+	require.NoError(t, afero.WriteFile(fs, "/testBroken/main.mptf.hcl", []byte(`
+transform update_in_place "broken" {
+  target_block_address = "resource.fake_resource.this"
+  condition            = "yes"
+  asraw {
+    tags = {}
+  }
+}
+`), 0644))
+	cf.mptfDirs = []string{"/testData", "/testBroken"}
+
+	restores, err := transform(false, context.Background(), "", false)
+	require.Error(t, err)
+	assert.Nil(t, restores)
+	assert.Contains(t, err.Error(), "no change has been written")
+	assert.Contains(t, err.Error(), "/testBroken")
+
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, terraformCode, string(tfFile))
+	files, err := afero.ReadDir(fs, "/testTerraform")
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+// failingWriteFs fails the first write to path.
+type failingWriteFs struct {
+	afero.Fs
+	path   string
+	failed bool
+}
+
+func (f *failingWriteFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if name == f.path && flag&(os.O_WRONLY|os.O_RDWR) != 0 && !f.failed {
+		f.failed = true
+		return nil, fmt.Errorf("cannot write %s", name)
+	}
+	return f.Fs.OpenFile(name, flag, perm)
+}
+
+func TestTransform_WriteFailureRestoresBackedUpModules(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	fs := stubTransformEnv(t, checkTestMptfConfig, terraformCode)
+	stub := gostub.Stub(&filesystem.Fs, &failingWriteFs{Fs: fs, path: "/testTerraform/main.tf"})
+	defer stub.Reset()
+
+	restores, err := transform(false, context.Background(), "", false)
+	require.Error(t, err)
+	assert.Nil(t, restores)
+	assert.Contains(t, err.Error(), "cannot write /testTerraform/main.tf")
+
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, terraformCode, string(tfFile))
+	backups, err := afero.Glob(fs, "/testTerraform/*"+backup.BackupExtension)
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+}
+
+func TestApplyTransforms_ParallelModulesReportInModuleOrder(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	fs := stubTransformEnv(t, checkTestMptfConfig, terraformCode)
+	cf.parallelism = 4
+	var moduleRefs []*pkg.TerraformModuleRef
+	for i := 0; i < 6; i++ {
+		dir := fmt.Sprintf("/modules/m%d", i)
+		code := terraformCode
+		if i%2 == 1 {
+			code = `resource "fake_resource" this {`
+		}
+		require.NoError(t, afero.WriteFile(fs, dir+"/main.tf", []byte(code), 0644))
+		ref, err := pkg.NewTerraformRootModuleRef(dir)
+		require.NoError(t, err)
+		moduleRefs = append(moduleRefs, ref)
+	}
+
+	_, err := applyTransforms(moduleRefs, []string{"/testData"}, nil, context.Background(), io.Discard)
+	require.Error(t, err)
+	errs := err.(*multierror.Error).Errors
+	require.Len(t, errs, 3)
+	for i, e := range errs {
+		assert.Contains(t, e.Error(), fmt.Sprintf("/modules/m%d", i*2+1))
+	}
+	for i := 0; i < 6; i += 2 {
+		content, err := afero.ReadFile(fs, fmt.Sprintf("/modules/m%d/main.tf", i))
+		require.NoError(t, err)
+		assert.Contains(t, string(content), "tags = {}")
+	}
+}
+
+func TestApplyTransforms_InvalidParallelism(t *testing.T) {
+	stubTransformEnv(t, checkTestMptfConfig, "")
+	cf.parallelism = 0
+	_, err := applyTransforms(nil, []string{"/testData"}, nil, context.Background(), io.Discard)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "--parallelism must be at least 1")
+}
+
+func TestTransform_VerifyIdempotent(t *testing.T) {
+	cases := []struct {
+		desc         string
+		skipIfExists bool
+		expectError  bool
+	}{
+		{
+			desc:        "new_block appends a duplicate on second pass",
+			expectError: true,
+		},
+		{
+			desc:         "new_block with skip_if_exists",
+			skipIfExists: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			terraformCode := `resource "fake_resource" this {
+}
+`
+			fs := stubTransformEnv(t, fmt.Sprintf(`
+transform "new_block" output {
+  new_block_type = "output"
+  filename       = "outputs.tf"
+  labels         = ["id"]
+  skip_if_exists = %t
+  asraw {
+    value = fake_resource.this.id
+  }
+}
+`, c.skipIfExists), terraformCode)
+
+			_, err := transform(false, context.Background(), "", true)
+			if !c.expectError {
+				require.NoError(t, err)
+				outputs, err := afero.ReadFile(fs, "/testTerraform/outputs.tf")
+				require.NoError(t, err)
+				assert.Contains(t, string(outputs), `output "id"`)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "transforms are not idempotent")
+			assert.Contains(t, err.Error(), "outputs.tf")
+			assert.Contains(t, err.Error(), "transform.new_block.output (new_block is not idempotent)")
+			exists, err := afero.Exists(fs, "/testTerraform/outputs.tf")
+			require.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+}
+
+func TestTransform_LaterPhaseSeesBlocksAddedByEarlierPhase(t *testing.T) {
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	fs := stubTransformEnv(t, `
+transform "new_block" that {
+  phase          = "create"
+  new_block_type = "resource"
+  filename       = "main.tf"
+  labels         = ["fake_resource", "that"]
+  asraw {
+    id = 1
+  }
+}
+
+data resource "fake_resource" {
+  resource_type = "fake_resource"
+}
+
+transform update_in_place "fake_resource" {
+  phase                = "tag"
+  for_each             = data.resource.fake_resource.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  asraw {
+    tags = {}
+  }
+}
+`, terraformCode)
+
+	_, err := transform(false, context.Background(), "", false)
+	require.NoError(t, err)
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, `resource "fake_resource" this {
+  tags = {}
+}
+
+resource "fake_resource" "that" {
+  id   = 1
+  tags = {}
+}
+`, string(tfFile))
+	journal, err := backup.ReadJournal("/testTerraform")
+	require.NoError(t, err)
+	var transforms []string
+	for _, cs := range journal.Changesets {
+		transforms = append(transforms, cs.Transform)
+	}
+	assert.Equal(t, []string{
+		"transform.new_block.that",
+		"transform.update_in_place.fake_resource[that]",
+		"transform.update_in_place.fake_resource[this]",
+	}, transforms)
+}
+
+func TestApplyTransforms_ConflictMode(t *testing.T) {
+	mptfConfig := `
+transform "update_in_place" first {
+  target_block_address = "resource.fake_resource.this"
+  asraw {
+    tags = {}
+  }
+}
+
+transform "update_in_place" second {
+  target_block_address = "resource.fake_resource.this"
+  asraw {
+    tags = { owner = "second" }
+  }
+}
+`
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	for _, mode := range []string{pkg.ConflictModeWarn, pkg.ConflictModeError} {
+		t.Run(mode, func(t *testing.T) {
+			fs := stubTransformEnv(t, mptfConfig, terraformCode)
+			cf.conflictMode = mode
+			moduleRef, err := pkg.NewTerraformRootModuleRef("/testTerraform")
+			require.NoError(t, err)
+			out := new(bytes.Buffer)
+			_, err = applyTransforms([]*pkg.TerraformModuleRef{moduleRef}, []string{"/testData"}, nil, context.Background(), out)
+			content, readErr := afero.ReadFile(fs, "/testTerraform/main.tf")
+			require.NoError(t, readErr)
+			if mode == pkg.ConflictModeError {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "transforms overwrite each other")
+				assert.NotContains(t, string(content), "tags")
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), "Warning: resource.fake_resource.this: `tags` written by transform.update_in_place.first")
+			assert.Contains(t, string(content), `owner = "second"`)
+		})
+	}
+}
+
+func TestApplyTransforms_ConflictsAcrossMptfDirsAreNotReported(t *testing.T) {
+	fs := stubTransformEnv(t, `
+transform "update_in_place" first {
+  target_block_address = "resource.fake_resource.this"
+  asraw {
+    tags = {}
+  }
+}
+`, `resource "fake_resource" this {
+}
+`)
+	require.NoError(t, afero.WriteFile(fs, "/testData2/main.mptf.hcl", []byte(`
+transform "update_in_place" second {
+  target_block_address = "resource.fake_resource.this"
+  asraw {
+    tags = { owner = "second" }
+  }
+}
+`), 0644))
+	cf.conflictMode = pkg.ConflictModeError
+	moduleRef, err := pkg.NewTerraformRootModuleRef("/testTerraform")
+	require.NoError(t, err)
+	out := new(bytes.Buffer)
+	_, err = applyTransforms([]*pkg.TerraformModuleRef{moduleRef}, []string{"/testData", "/testData2"}, nil, context.Background(), out)
+	require.NoError(t, err)
+	assert.NotContains(t, out.String(), "Warning:")
+	content, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `owner = "second"`)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"os"
 	"testing"
 )
@@ -79,3 +80,71 @@ resource "fake_resource" that {
 	require.NoError(t, err)
 	assert.Equal(t, terraformCode, string(backupFileContent))
 }
+
+func TestTransformPlan_PrintsDiffWithoutTouchingDisk(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "/testData/main.mptf.hcl", []byte(`
+data resource "fake_resource" {
+  resource_type = "fake_resource"
+}
+
+transform update_in_place "fake_resource" {
+ for_each = data.resource.fake_resource.result.fake_resource
+ target_block_address = each.value.mptf.block_address
+ asraw {
+   tags = {}
+ }
+}
+`), 0644)
+	terraformCode := `resource "fake_resource" this {
+}
+`
+	_ = afero.WriteFile(fs, "/testTerraform/main.tf", []byte(terraformCode), 0644)
+	stub := gostub.Stub(&filesystem.Fs, fs).Stub(
+		&os.Args, []string{
+			"mapotf",
+			"transform",
+			"--plan",
+			"--tf-dir", "/testTerraform",
+			"--mptf-dir", "/testData",
+		}).Stub(&pkg.AbsDir, func(dir string) (string, error) {
+		return dir, nil
+	})
+	defer stub.Reset()
+
+	mptfArgs, nonMptfArgs := cmd.FilterArgs(os.Args)
+	os.Args = mptfArgs
+	cmd.NonMptfArgs = nonMptfArgs
+	output := captureStdout(t, func() {
+		cmd.Execute(context.Background())
+	})
+	expectedDiff := `--- a/main.tf
++++ b/main.tf
+@@ -1,2 +1,3 @@
+ resource "fake_resource" this {
++  tags = {}
+ }
+`
+	assert.Equal(t, expectedDiff, output)
+	tfFile, err := afero.ReadFile(fs, "/testTerraform/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, terraformCode, string(tfFile))
+	files, err := afero.ReadDir(fs, "/testTerraform")
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	stub := gostub.Stub(&os.Stdout, w)
+	output := make(chan string)
+	go func() {
+		content, _ := io.ReadAll(r)
+		output <- string(content)
+	}()
+	f()
+	stub.Reset()
+	_ = w.Close()
+	return <-output
+}
@@ -5,16 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
 	"strings"
 )
 
 var cf = &commonFlags{}
 
 type commonFlags struct {
-	tfDir        string
-	mptfDirs     []string
-	mptfVars     []string
-	mptfVarFiles []string
+	tfDir            string
+	mptfDirs         []string
+	mptfVars         []string
+	mptfVarFiles     []string
+	openTofu         bool
+	includeOverrides bool
+	backupStrategy   string
+	parallelism      int
+	conflictMode     string
+	terraformOptions pkg.TerraformOptions
 }
 
 type localizedMptfDir struct {
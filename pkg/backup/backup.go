@@ -1,7 +1,9 @@
 package backup
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,9 +16,9 @@ const BackupExtension = ".mptfbackup"
 const NewFileExtension = ".mptfnew"
 
 func BackupFolder(dir string) error {
-	terraformFile, err := afero.Glob(filesystem.Fs, filepath.Join(dir, "*.tf"))
+	terraformFile, err := terraformFiles(dir)
 	if err != nil {
-		return fmt.Errorf("cannot list terraform files in %s:%+v", dir, err)
+		return err
 	}
 	for _, file := range terraformFile {
 		backupFile := file + BackupExtension
@@ -45,12 +47,38 @@ func BackupFolder(dir string) error {
 	return nil
 }
 
+// terraformFiles lists the files a backup covers. `.tofu` files are only
+// loaded in OpenTofu mode, but backing them up unconditionally is harmless.
+func terraformFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"*.tf", "*.tofu"} {
+		matches, err := afero.Glob(filesystem.Fs, filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("cannot list terraform files in %s:%+v", dir, err)
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// Reset restores dir from its backup, whichever strategy created it.
 func Reset(dir string) error {
-	err := restoreBackup(dir)
+	record, err := readGitBackup(dir)
 	if err != nil {
 		return err
 	}
-	return removeNewFiles(dir)
+	if record != nil {
+		if err = restoreGitBackup(dir, record); err != nil {
+			return err
+		}
+	}
+	if err = restoreBackup(dir); err != nil {
+		return err
+	}
+	if err = removeNewFiles(dir); err != nil {
+		return err
+	}
+	return removeJournal(dir)
 }
 
 func removeNewFiles(dir string) error {
@@ -114,7 +142,14 @@ func ClearBackup(dir string) error {
 			return fmt.Errorf("cannot delete backup file %s:%+v", backupFile, err)
 		}
 	}
-	return nil
+	if err = removeGitBackup(dir); err != nil {
+		return err
+	}
+	return removeJournal(dir)
+}
+
+func isNotExist(err error) bool {
+	return os.IsNotExist(err) || errors.Is(err, fs.ErrNotExist)
 }
 
 func getFilePerm(originalFile string, backupFile string, err error) (os.FileInfo, error) {
@@ -34,6 +34,21 @@ func TestMetaProgrammingTFPlan_OnlyTransformThatHasTargetShouldBeInThePlan(t *te
 	assert.False(t, exists)
 }
 
+func TestBackupFolder_ShouldBackupTofuFiles(t *testing.T) {
+	dir := "cfg"
+	expectedContent := `resource "fake_resource" this {
+}`
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join(dir, "main.tofu"): expectedContent,
+	}))
+	defer stub.Reset()
+	err := BackupFolder(dir)
+	require.NoError(t, err)
+	content, err := afero.ReadFile(filesystem.Fs, filepath.Join(dir, "main.tofu"+BackupExtension))
+	require.NoError(t, err)
+	assert.Equal(t, expectedContent, string(content))
+}
+
 func TestBackupFolder_BackupFileAlreadyExists(t *testing.T) {
 	dir := "cfg"
 	originalContent := `resource "fake_resource" this {
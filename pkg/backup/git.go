@@ -0,0 +1,338 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	gitstorage "github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/spf13/afero"
+)
+
+// Backup strategies accepted by `--backup-strategy`.
+const (
+	// StrategyFile copies every Terraform file to a `.mptfbackup` file and
+	// marks created files with a `.mptfnew` file.
+	StrategyFile = "file"
+	// StrategyGit records the HEAD commit and the files transforms change in
+	// the git directory, and restores those files from HEAD. The module must
+	// be a clean git worktree.
+	StrategyGit = "git"
+	// StrategyAuto uses StrategyGit when the module is a clean git worktree
+	// and StrategyFile otherwise.
+	StrategyAuto = "auto"
+)
+
+// gitBackupDir is the folder, inside the git directory, that holds the git
+// backup records, so backing up adds nothing to the worktree.
+const gitBackupDir = "mapotf"
+const gitBackupFileName = "git-backup.json"
+
+type gitBackup struct {
+	Head     string   `json:"head"`
+	Files    []string `json:"files"`
+	NewFiles []string `json:"new_files"`
+}
+
+// gitModule locates the git worktree dir lives in.
+type gitModule struct {
+	repo *git.Repository
+	// prefix is dir relative to the worktree root, in git's slash form, with
+	// a trailing slash unless dir is the root.
+	prefix string
+	gitDir string
+}
+
+func openGitModule(dir string) (*gitModule, error) {
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %+v", dir, err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("cannot open git worktree of %s: %+v", dir, err)
+	}
+	root, err := filepath.EvalSymlinks(wt.Filesystem.Root())
+	if err != nil {
+		return nil, err
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, realDir)
+	if err != nil {
+		return nil, err
+	}
+	prefix := ""
+	if rel != "." {
+		prefix = filepath.ToSlash(rel) + "/"
+	}
+	storage, ok := repo.Storer.(*gitstorage.Storage)
+	if !ok {
+		return nil, fmt.Errorf("cannot locate the git directory of %s", dir)
+	}
+	return &gitModule{repo: repo, prefix: prefix, gitDir: storage.Filesystem().Root()}, nil
+}
+
+// recordPath is where the git backup record of the module is kept: in the
+// git directory, under the module's path relative to the worktree root.
+func (g *gitModule) recordPath() string {
+	return filepath.Join(g.gitDir, gitBackupDir, filepath.FromSlash(g.prefix), gitBackupFileName)
+}
+
+// dirtyFiles lists the files under the module dir that differ from HEAD or
+// are untracked, ignoring JournalDir.
+func (g *gitModule) dirtyFiles() ([]string, error) {
+	wt, err := g.repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return nil, fmt.Errorf("cannot get git status: %+v", err)
+	}
+	var dirty []string
+	for path, s := range status {
+		if !strings.HasPrefix(path, g.prefix) || strings.HasPrefix(path, g.prefix+JournalDir+"/") {
+			continue
+		}
+		if s.Worktree != git.Unmodified || s.Staging != git.Unmodified {
+			dirty = append(dirty, path)
+		}
+	}
+	sort.Strings(dirty)
+	return dirty, nil
+}
+
+// IsCleanGitWorktree reports whether dir is inside a git worktree with no
+// uncommitted change under dir.
+func IsCleanGitWorktree(dir string) bool {
+	g, err := openGitModule(dir)
+	if err != nil {
+		return false
+	}
+	dirty, err := g.dirtyFiles()
+	return err == nil && len(dirty) == 0
+}
+
+// HasGitBackup reports whether dir was backed up with StrategyGit.
+func HasGitBackup(dir string) (bool, error) {
+	record, err := readGitBackup(dir)
+	return record != nil, err
+}
+
+// GitBackupFolder is StrategyGit's BackupFolder: instead of copying files it
+// records HEAD and files, the names of the files of dir transforms are about
+// to change, which must all be committed. An existing record is extended but
+// keeps its HEAD, so HEAD stays the commit before the first transform.
+func GitBackupFolder(dir string, files []string) error {
+	g, err := openGitModule(dir)
+	if err != nil {
+		return err
+	}
+	record, err := g.readRecord()
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		dirty, err := g.dirtyFiles()
+		if err != nil {
+			return err
+		}
+		if len(dirty) > 0 {
+			return fmt.Errorf("cannot use git backup, %s has uncommitted changes: %s", dir, strings.Join(dirty, ", "))
+		}
+		head, err := g.repo.Head()
+		if err != nil {
+			return fmt.Errorf("cannot resolve HEAD of %s: %+v", dir, err)
+		}
+		record = &gitBackup{
+			Head:     head.Hash().String(),
+			Files:    []string{},
+			NewFiles: []string{},
+		}
+	}
+	commit, err := g.repo.CommitObject(plumbing.NewHash(record.Head))
+	if err != nil {
+		return fmt.Errorf("cannot read commit %s: %+v", record.Head, err)
+	}
+	for _, name := range files {
+		if slices.Contains(record.Files, name) || slices.Contains(record.NewFiles, name) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		f, err := commit.File(g.prefix + name)
+		if err != nil {
+			return fmt.Errorf("cannot use git backup, %s is not committed: %+v", path, err)
+		}
+		committed, err := f.Contents()
+		if err != nil {
+			return fmt.Errorf("cannot read %s in commit %s: %+v", name, record.Head, err)
+		}
+		// Restoring a file that changed since the record was created would
+		// lose that change.
+		content, err := afero.ReadFile(filesystem.Fs, path)
+		if err != nil {
+			return fmt.Errorf("cannot read terraform file %s:%+v", path, err)
+		}
+		if string(content) != committed {
+			return fmt.Errorf("cannot use git backup, %s has changed since commit %s", path, record.Head)
+		}
+		record.Files = append(record.Files, name)
+	}
+	sort.Strings(record.Files)
+	return g.writeRecord(record)
+}
+
+// AdoptNewFiles moves the files transforms created in a dir backed up with
+// StrategyGit from their `.mptfnew` indicator into the git backup record, so
+// no indicator is left in the worktree. It does nothing for StrategyFile.
+func AdoptNewFiles(dir string) error {
+	record, err := readGitBackup(dir)
+	if err != nil || record == nil {
+		return err
+	}
+	indicators, err := afero.Glob(filesystem.Fs, filepath.Join(dir, "*"+NewFileExtension))
+	if err != nil {
+		return fmt.Errorf("cannot list new file indicators in %s:%+v", dir, err)
+	}
+	if len(indicators) == 0 {
+		return nil
+	}
+	for _, indicator := range indicators {
+		name := strings.TrimSuffix(filepath.Base(indicator), NewFileExtension)
+		record.NewFiles = append(record.NewFiles, name)
+		if err = filesystem.Fs.Remove(indicator); err != nil {
+			return fmt.Errorf("cannot delete new file indicator %s:%+v", indicator, err)
+		}
+	}
+	g, err := openGitModule(dir)
+	if err != nil {
+		return err
+	}
+	return g.writeRecord(record)
+}
+
+// restoreGitBackup checks the recorded files out of the recorded commit and
+// deletes the files transforms created.
+func restoreGitBackup(dir string, record *gitBackup) error {
+	g, err := openGitModule(dir)
+	if err != nil {
+		return err
+	}
+	commit, err := g.repo.CommitObject(plumbing.NewHash(record.Head))
+	if err != nil {
+		return fmt.Errorf("cannot read commit %s: %+v", record.Head, err)
+	}
+	for _, name := range record.Files {
+		f, err := commit.File(g.prefix + name)
+		if err != nil {
+			return fmt.Errorf("cannot find %s in commit %s: %+v", name, record.Head, err)
+		}
+		content, err := f.Contents()
+		if err != nil {
+			return fmt.Errorf("cannot read %s in commit %s: %+v", name, record.Head, err)
+		}
+		path := filepath.Join(dir, name)
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		if err = afero.WriteFile(filesystem.Fs, path, []byte(content), mode); err != nil {
+			return fmt.Errorf("cannot write original file %s:%+v", path, err)
+		}
+	}
+	for _, name := range record.NewFiles {
+		path := filepath.Join(dir, name)
+		if err = filesystem.Fs.Remove(path); err != nil && !isNotExist(err) {
+			return fmt.Errorf("cannot delete new file %s:%+v", path, err)
+		}
+	}
+	return g.removeRecord()
+}
+
+// readGitBackup reads the git backup record of dir. A dir without a record,
+// or outside any git repository, has none.
+func readGitBackup(dir string) (*gitBackup, error) {
+	g, err := openGitModule(dir)
+	if err != nil {
+		return nil, nil
+	}
+	return g.readRecord()
+}
+
+func (g *gitModule) readRecord() (*gitBackup, error) {
+	path := g.recordPath()
+	exist, err := afero.Exists(filesystem.Fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check git backup %s:%+v", path, err)
+	}
+	if !exist {
+		return nil, nil
+	}
+	content, err := afero.ReadFile(filesystem.Fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read git backup %s:%+v", path, err)
+	}
+	record := &gitBackup{}
+	if err = json.Unmarshal(content, record); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal git backup %s:%+v", path, err)
+	}
+	return record, nil
+}
+
+func (g *gitModule) writeRecord(record *gitBackup) error {
+	path := g.recordPath()
+	if err := filesystem.Fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create backup folder %s:%+v", filepath.Dir(path), err)
+	}
+	content, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal git backup %s:%+v", path, err)
+	}
+	if err = afero.WriteFile(filesystem.Fs, path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write git backup %s:%+v", path, err)
+	}
+	return nil
+}
+
+// removeRecord deletes the git backup record, and the folders it leaves
+// empty in the git directory.
+func (g *gitModule) removeRecord() error {
+	path := g.recordPath()
+	exist, err := afero.Exists(filesystem.Fs, path)
+	if err != nil {
+		return fmt.Errorf("cannot check git backup %s:%+v", path, err)
+	}
+	if !exist {
+		return nil
+	}
+	if err = filesystem.Fs.Remove(path); err != nil {
+		return fmt.Errorf("cannot delete git backup %s:%+v", path, err)
+	}
+	root := filepath.Join(g.gitDir, gitBackupDir)
+	for folder := filepath.Dir(path); strings.HasPrefix(folder, root); folder = filepath.Dir(folder) {
+		if empty, err := afero.IsEmpty(filesystem.Fs, folder); err != nil || !empty {
+			break
+		}
+		if err = filesystem.Fs.Remove(folder); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func removeGitBackup(dir string) error {
+	g, err := openGitModule(dir)
+	if err != nil {
+		return nil
+	}
+	return g.removeRecord()
+}
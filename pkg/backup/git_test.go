@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const originalMainTf = `resource "fake_resource" this {
+}
+`
+
+func initGitModule(t *testing.T) string {
+	stub := gostub.Stub(&filesystem.Fs, afero.NewOsFs())
+	t.Cleanup(stub.Reset)
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	require.NoError(t, err)
+	dir := filepath.Join(root, "module")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte(originalMainTf), 0644))
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Add("module/main.tf")
+	require.NoError(t, err)
+	_, err = wt.Commit("init", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	return dir
+}
+
+func TestGitBackupFolder_ResetRestoresFromHead(t *testing.T) {
+	dir := initGitModule(t)
+	require.True(t, IsCleanGitWorktree(dir))
+	require.NoError(t, GitBackupFolder(dir, []string{"main.tf"}))
+	exists, err := afero.Exists(filesystem.Fs, filepath.Join(dir, JournalDir))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.True(t, IsCleanGitWorktree(dir))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("changed"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.tf"), []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.tf"+NewFileExtension), nil, 0644))
+	require.NoError(t, AdoptNewFiles(dir))
+	exists, err = afero.Exists(filesystem.Fs, filepath.Join(dir, "new.tf"+NewFileExtension))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	backups, err := afero.Glob(filesystem.Fs, filepath.Join(dir, "*"+BackupExtension))
+	require.NoError(t, err)
+	assert.Empty(t, backups)
+
+	require.NoError(t, Reset(dir))
+	content, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, originalMainTf, string(content))
+	exists, err = afero.Exists(filesystem.Fs, filepath.Join(dir, "new.tf"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = afero.Exists(filesystem.Fs, filepath.Join(dir, JournalDir))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestGitBackupFolder_DirtyWorktreeShouldFail(t *testing.T) {
+	dir := initGitModule(t)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("uncommitted"), 0644))
+	assert.False(t, IsCleanGitWorktree(dir))
+	err := GitBackupFolder(dir, []string{"main.tf"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "uncommitted changes")
+	exists, err := HasGitBackup(dir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestClearBackup_ShouldRemoveGitBackup(t *testing.T) {
+	dir := initGitModule(t)
+	require.NoError(t, GitBackupFolder(dir, []string{"main.tf"}))
+	require.NoError(t, ClearBackup(dir))
+	exists, err := HasGitBackup(dir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = afero.Exists(filesystem.Fs, filepath.Join(filepath.Dir(dir), ".git", gitBackupDir))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestGitBackupFolder_RecordsOnlyChangedFiles(t *testing.T) {
+	dir := initGitModule(t)
+	commitFile(t, dir, "variables.tf", "variable \"name\" {}\n")
+	require.NoError(t, GitBackupFolder(dir, nil))
+	record, err := readGitBackup(dir)
+	require.NoError(t, err)
+	assert.Empty(t, record.Files)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.tf"), []byte("changed"), 0644))
+	require.NoError(t, GitBackupFolder(dir, []string{"variables.tf"}))
+	record, err = readGitBackup(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"variables.tf"}, record.Files)
+
+	require.NoError(t, Reset(dir))
+	content, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(content))
+}
+
+func TestGitBackupFolder_FileChangedSinceRecordShouldFail(t *testing.T) {
+	dir := initGitModule(t)
+	commitFile(t, dir, "variables.tf", "variable \"name\" {}\n")
+	require.NoError(t, GitBackupFolder(dir, []string{"main.tf"}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "variables.tf"), []byte("edited"), 0644))
+
+	err := GitBackupFolder(dir, []string{"variables.tf"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has changed since commit")
+}
+
+func commitFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	_, err = wt.Add("module/" + name)
+	require.NoError(t, err)
+	_, err = wt.Commit("add "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+}
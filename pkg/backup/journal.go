@@ -0,0 +1,349 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+// JournalDir is the folder, inside each module directory, that holds the
+// transform journal.
+const JournalDir = ".mptf"
+const journalFileName = "journal.json"
+
+// contextLines is the number of unchanged lines recorded on each side of a
+// hunk. They locate the hunk again when reverting and tell whether the code
+// around it has drifted since.
+const contextLines = 3
+
+// Hunk is one contiguous change a transform made to a file. Start and End
+// are the byte range NewText occupied in the file right after the transform
+// ran.
+type Hunk struct {
+	File          string `json:"file"`
+	Start         int    `json:"start"`
+	End           int    `json:"end"`
+	OldText       string `json:"old_text"`
+	NewText       string `json:"new_text"`
+	ContextBefore string `json:"context_before"`
+	ContextAfter  string `json:"context_after"`
+}
+
+// Changeset records every hunk one applied transform produced.
+type Changeset struct {
+	Transform string `json:"transform"`
+	Hunks     []Hunk `json:"hunks"`
+}
+
+// Journal lists the changesets applied to a module directory, in the order
+// they were applied.
+type Journal struct {
+	Changesets []Changeset `json:"changesets"`
+}
+
+func journalPath(dir string) string {
+	return filepath.Join(dir, JournalDir, journalFileName)
+}
+
+// Diff returns the line-based hunks that turn before into after.
+func Diff(file, before, after string) []Hunk {
+	a, b := splitLines(before), splitLines(after)
+	offsets := make([]int, len(b)+1)
+	for i, l := range b {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+	var hunks []Hunk
+	ops := difflib.NewMatcher(a, b).GetOpCodes()
+	for i, op := range ops {
+		if op.Tag == 'e' {
+			continue
+		}
+		// Context never reaches into a neighbouring hunk, so reverting one
+		// hunk can't make the context of another look drifted.
+		ctxStart, ctxEnd := op.J1, op.J2
+		if i > 0 && ops[i-1].Tag == 'e' {
+			ctxStart = max(op.J1-contextLines, ops[i-1].J1)
+		}
+		if i < len(ops)-1 && ops[i+1].Tag == 'e' {
+			ctxEnd = min(op.J2+contextLines, ops[i+1].J2)
+		}
+		hunks = append(hunks, Hunk{
+			File:          file,
+			Start:         offsets[op.J1],
+			End:           offsets[op.J2],
+			OldText:       strings.Join(a[op.I1:op.I2], ""),
+			NewText:       strings.Join(b[op.J1:op.J2], ""),
+			ContextBefore: strings.Join(b[ctxStart:op.J1], ""),
+			ContextAfter:  strings.Join(b[op.J2:ctxEnd], ""),
+		})
+	}
+	return hunks
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// ReadJournal reads the journal of dir. A directory without a journal has an
+// empty one.
+func ReadJournal(dir string) (*Journal, error) {
+	path := journalPath(dir)
+	exist, err := afero.Exists(filesystem.Fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check journal %s:%+v", path, err)
+	}
+	journal := &Journal{}
+	if !exist {
+		return journal, nil
+	}
+	content, err := afero.ReadFile(filesystem.Fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read journal %s:%+v", path, err)
+	}
+	if err = json.Unmarshal(content, journal); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal journal %s:%+v", path, err)
+	}
+	return journal, nil
+}
+
+// AppendJournal records changesets at the end of the journal of dir.
+func AppendJournal(dir string, changesets []Changeset) error {
+	if len(changesets) == 0 {
+		return nil
+	}
+	journal, err := ReadJournal(dir)
+	if err != nil {
+		return err
+	}
+	journal.Changesets = append(journal.Changesets, changesets...)
+	return writeJournal(dir, journal)
+}
+
+func writeJournal(dir string, journal *Journal) error {
+	if len(journal.Changesets) == 0 {
+		return removeJournal(dir)
+	}
+	path := journalPath(dir)
+	if err := filesystem.Fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("cannot create journal folder %s:%+v", filepath.Dir(path), err)
+	}
+	content, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal journal %s:%+v", path, err)
+	}
+	if err = afero.WriteFile(filesystem.Fs, path, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write journal %s:%+v", path, err)
+	}
+	return nil
+}
+
+func removeJournal(dir string) error {
+	path := journalPath(dir)
+	exist, err := afero.Exists(filesystem.Fs, path)
+	if err != nil {
+		return fmt.Errorf("cannot check journal %s:%+v", path, err)
+	}
+	if !exist {
+		return nil
+	}
+	if err = filesystem.Fs.Remove(path); err != nil {
+		return fmt.Errorf("cannot delete journal %s:%+v", path, err)
+	}
+	removeEmptyJournalDir(dir)
+	return nil
+}
+
+func removeEmptyJournalDir(dir string) {
+	journalDir := filepath.Join(dir, JournalDir)
+	if empty, err := afero.IsEmpty(filesystem.Fs, journalDir); err == nil && empty {
+		_ = filesystem.Fs.Remove(journalDir)
+	}
+}
+
+// Revert holds the reverted content of a module directory, computed by
+// PlanRevert without writing anything.
+type Revert struct {
+	dir      string
+	journal  *Journal
+	contents map[string]string
+	changed  bool
+	// Reverted is the number of changesets reverted for each address.
+	Reverted map[string]int
+}
+
+// RevertTransform reverts the hunks recorded for transform address in dir,
+// and for every instance of it when address has no `[key]` suffix. Later
+// changesets are reverted first. Nothing is written unless every hunk can be
+// reverted: a hunk whose text or surrounding lines changed since it was
+// recorded fails the whole revert. It returns the number of changesets
+// reverted.
+func RevertTransform(dir, address string) (int, error) {
+	r, err := PlanRevert(dir, []string{address})
+	if err != nil {
+		return 0, err
+	}
+	return r.Reverted[address], r.Write()
+}
+
+// PlanRevert reverts, in memory, the hunks recorded in dir for every one of
+// addresses, the way RevertTransform does for a single address. It fails when
+// any hunk has drifted. Call Write to write the result.
+func PlanRevert(dir string, addresses []string) (*Revert, error) {
+	journal, err := ReadJournal(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Revert{
+		dir:      dir,
+		journal:  journal,
+		contents: make(map[string]string),
+		Reverted: make(map[string]int),
+	}
+	var kept []Changeset
+	for i := len(journal.Changesets) - 1; i >= 0; i-- {
+		cs := journal.Changesets[i]
+		matched := false
+		for _, address := range addresses {
+			if cs.Transform == address || strings.HasPrefix(cs.Transform, address+"[") {
+				r.Reverted[address]++
+				matched = true
+			}
+		}
+		if !matched {
+			kept = append(kept, cs)
+			continue
+		}
+		r.changed = true
+		if err = revertChangeset(dir, cs, r.contents); err != nil {
+			return nil, fmt.Errorf("cannot revert %s: %+v", cs.Transform, err)
+		}
+	}
+	// kept was collected backwards.
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	r.journal = &Journal{Changesets: kept}
+	return r, nil
+}
+
+// Write writes the reverted files and the journal without the reverted
+// changesets. It does nothing when no changeset was reverted.
+func (r *Revert) Write() error {
+	if !r.changed {
+		return nil
+	}
+	for _, file := range sortedKeys(r.contents) {
+		if err := writeRevertedFile(filepath.Join(r.dir, file), r.contents[file]); err != nil {
+			return err
+		}
+	}
+	return writeJournal(r.dir, r.journal)
+}
+
+func revertChangeset(dir string, cs Changeset, contents map[string]string) error {
+	hunks := append([]Hunk{}, cs.Hunks...)
+	sort.SliceStable(hunks, func(i, j int) bool {
+		return hunks[i].Start > hunks[j].Start
+	})
+	for _, h := range hunks {
+		content, ok := contents[h.File]
+		if !ok {
+			path := filepath.Join(dir, h.File)
+			b, err := afero.ReadFile(filesystem.Fs, path)
+			if err != nil {
+				return fmt.Errorf("cannot read %s:%+v", path, err)
+			}
+			content = string(b)
+		}
+		start, ok := locateHunk(content, h)
+		if !ok {
+			return fmt.Errorf("%s has drifted since the transform was applied", h.File)
+		}
+		contents[h.File] = content[:start] + h.OldText + content[start+len(h.NewText):]
+	}
+	return nil
+}
+
+// locateHunk returns where h's NewText starts in content. The hunk must still
+// be surrounded by its recorded context; when it occurs more than once the
+// occurrence nearest to the recorded offset wins.
+func locateHunk(content string, h Hunk) (int, bool) {
+	needle := h.ContextBefore + h.NewText + h.ContextAfter
+	best, found := 0, false
+	for offset := 0; offset <= len(content)-len(needle); {
+		i := strings.Index(content[offset:], needle)
+		if i < 0 {
+			break
+		}
+		start := offset + i + len(h.ContextBefore)
+		if !found || abs(start-h.Start) < abs(best-h.Start) {
+			best, found = start, true
+		}
+		offset += i + 1
+	}
+	return best, found
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// writeRevertedFile writes the reverted content of path. A file the
+// transforms created is deleted once reverting leaves it empty.
+func writeRevertedFile(path, content string) error {
+	newFileIndicator := path + NewFileExtension
+	hasIndicator, err := afero.Exists(filesystem.Fs, newFileIndicator)
+	if err != nil {
+		return fmt.Errorf("cannot check new file indicator %s:%+v", newFileIndicator, err)
+	}
+	record, err := readGitBackup(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	isNewFile := hasIndicator || (record != nil && slices.Contains(record.NewFiles, filepath.Base(path)))
+	if isNewFile && strings.TrimSpace(content) == "" {
+		if err = filesystem.Fs.Remove(path); err != nil {
+			return fmt.Errorf("cannot delete new file %s:%+v", path, err)
+		}
+		if hasIndicator {
+			if err = filesystem.Fs.Remove(newFileIndicator); err != nil {
+				return fmt.Errorf("cannot delete new file indicator %s:%+v", newFileIndicator, err)
+			}
+		}
+		return nil
+	}
+	info, err := filesystem.Fs.Stat(path)
+	if err != nil {
+		return fmt.Errorf("cannot get permission of terraform file %s:%+v", path, err)
+	}
+	if err = afero.WriteFile(filesystem.Fs, path, []byte(content), info.Mode()); err != nil {
+		return fmt.Errorf("cannot write terraform file %s:%+v", path, err)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
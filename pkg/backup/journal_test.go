@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"path/filepath"
+	"testing"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	before := "a\nb\nc\nd\ne\nf\ng\nh\ni\n"
+	after := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	hunks := Diff("main.tf", before, after)
+	assert.Equal(t, []Hunk{
+		{
+			File:          "main.tf",
+			Start:         2,
+			End:           4,
+			OldText:       "b\n",
+			NewText:       "B\n",
+			ContextBefore: "a\n",
+			ContextAfter:  "c\nd\ne\n",
+		},
+		{
+			File:          "main.tf",
+			Start:         18,
+			End:           20,
+			OldText:       "",
+			NewText:       "j\n",
+			ContextBefore: "g\nh\ni\n",
+			ContextAfter:  "",
+		},
+	}, hunks)
+}
+
+func TestRevertTransform_KeepsLaterChanges(t *testing.T) {
+	dir := "cfg"
+	original := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	first := "a\nB\nc\nd\ne\nf\ng\nh\n"
+	second := "x\na\nB\nc\nd\ne\nf\ng\nH\n"
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join(dir, "main.tf"): second,
+	}))
+	defer stub.Reset()
+	require.NoError(t, AppendJournal(dir, []Changeset{
+		{
+			Transform: "transform.update_in_place.first",
+			Hunks:     Diff("main.tf", original, first),
+		},
+	}))
+	require.NoError(t, AppendJournal(dir, []Changeset{
+		{
+			Transform: "transform.update_in_place.second",
+			Hunks:     Diff("main.tf", first, second),
+		},
+	}))
+
+	reverted, err := RevertTransform(dir, "transform.update_in_place.first")
+	require.NoError(t, err)
+	assert.Equal(t, 1, reverted)
+	content, err := afero.ReadFile(filesystem.Fs, filepath.Join(dir, "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, "x\na\nb\nc\nd\ne\nf\ng\nH\n", string(content))
+	journal, err := ReadJournal(dir)
+	require.NoError(t, err)
+	require.Len(t, journal.Changesets, 1)
+	assert.Equal(t, "transform.update_in_place.second", journal.Changesets[0].Transform)
+
+	reverted, err = RevertTransform(dir, "transform.update_in_place.first")
+	require.NoError(t, err)
+	assert.Equal(t, 0, reverted)
+}
+
+func TestReset_ShouldRemoveJournal(t *testing.T) {
+	dir := "cfg"
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join(dir, "main.tf"): "new",
+	}))
+	defer stub.Reset()
+	require.NoError(t, AppendJournal(dir, []Changeset{
+		{
+			Transform: "transform.update_in_place.this",
+			Hunks:     Diff("main.tf", "old\n", "new\n"),
+		},
+	}))
+	require.NoError(t, Reset(dir))
+	exists, err := afero.Exists(filesystem.Fs, filepath.Join(dir, JournalDir, "journal.json"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
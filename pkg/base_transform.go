@@ -1,12 +1,20 @@
 package pkg
 
 import (
+	"fmt"
+	"slices"
+
 	"github.com/Azure/golden"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type Transform interface {
 	golden.ApplyBlock
 	Transform()
+	// Idempotent reports whether applying the transform to its own output
+	// changes nothing, so running `mapotf transform` twice is safe.
+	Idempotent() bool
 }
 
 type BaseTransform struct{}
@@ -15,3 +23,57 @@ func (bt *BaseTransform) BlockType() string       { return "transform" }
 func (bt *BaseTransform) AddressLength() int      { return 3 }
 func (bt *BaseTransform) CanExecutePrePlan() bool { return false }
 func (bt *BaseTransform) Transform()              {}
+func (bt *BaseTransform) Idempotent() bool        { return true }
+
+// transformEnabled evaluates the optional `condition` meta-argument of a
+// transform, taken out of its block by stripTransformMetaAttributes. A
+// transform without one is always enabled.
+func transformEnabled(t Transform, condition *hclsyntax.Attribute) (bool, error) {
+	if condition == nil {
+		return true, nil
+	}
+	value, diag := condition.Expr.Value(t.EvalContext())
+	if diag.HasErrors() {
+		return false, diag
+	}
+	if value.IsNull() || !value.IsKnown() || !value.Type().Equals(cty.Bool) {
+		return false, fmt.Errorf("condition must be a known bool value, got %s", value.GoString())
+	}
+	return value.True(), nil
+}
+
+// transformMetaAttributeNames are the meta-arguments of transform blocks,
+// evaluated by MetaProgrammingTFPlan rather than decoded into the block.
+var transformMetaAttributeNames = []string{"condition", "phase"}
+
+// stripTransformMetaAttributes takes the meta-arguments out of the bodies of
+// the transform blocks among hclBlocks, so golden decodes what is left into
+// the transform and still rejects them in any other block. It returns the
+// meta-arguments by transform block address, which the for_each instances of
+// a transform share.
+func stripTransformMetaAttributes(hclBlocks []*golden.HclBlock) map[string]hclsyntax.Attributes {
+	r := make(map[string]hclsyntax.Attributes)
+	for _, hb := range hclBlocks {
+		if hb.Type != "transform" {
+			continue
+		}
+		meta := make(hclsyntax.Attributes)
+		body := *hb.Body
+		body.Attributes = make(hclsyntax.Attributes, len(hb.Body.Attributes))
+		for name, attr := range hb.Body.Attributes {
+			if slices.Contains(transformMetaAttributeNames, name) {
+				meta[name] = attr
+				continue
+			}
+			body.Attributes[name] = attr
+		}
+		if len(meta) == 0 {
+			continue
+		}
+		block := *hb.Block
+		block.Body = &body
+		hb.Block = &block
+		r[transformBlockAddress(hb)] = meta
+	}
+	return r
+}
@@ -0,0 +1,155 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Conflict modes accepted by MetaProgrammingTFPlan.SetConflictMode.
+const (
+	// ConflictModeWarn records conflicts, see MetaProgrammingTFPlan.Conflicts.
+	ConflictModeWarn = "warn"
+	// ConflictModeError fails MetaProgrammingTFPlan.Apply before anything is
+	// saved.
+	ConflictModeError = "error"
+	// ConflictModeOff doesn't track writes at all.
+	ConflictModeOff = "off"
+)
+
+// CheckConflictMode fails when mode isn't a conflict mode. An empty mode
+// means ConflictModeWarn.
+func CheckConflictMode(mode string) error {
+	switch mode {
+	case "", ConflictModeWarn, ConflictModeError, ConflictModeOff:
+		return nil
+	}
+	return fmt.Errorf("unknown conflict mode %q, must be one of `%s`, `%s` or `%s`", mode, ConflictModeWarn, ConflictModeError, ConflictModeOff)
+}
+
+// TransformWrite names a transform and where it is declared.
+type TransformWrite struct {
+	Address string
+	Range   hcl.Range
+}
+
+func (w TransformWrite) String() string {
+	return fmt.Sprintf("%s (%s)", w.Address, w.Range.String())
+}
+
+// Conflict is an attribute one transform wrote and a later transform
+// overwrote, without depending on the first one.
+type Conflict struct {
+	BlockAddress  string
+	AttributePath string
+	First         TransformWrite
+	Second        TransformWrite
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: `%s` written by %s is overwritten by %s", c.BlockAddress, c.AttributePath, c.First, c.Second)
+}
+
+// writeTracker remembers which transform last wrote each attribute path of
+// each root block during one Apply. Apply runs one phase of one mptf dir, so
+// writes from other phases or mptf dirs, which run in a fixed order anyway,
+// are never compared.
+type writeTracker struct {
+	order     *transformOrder
+	writers   map[string]TransformWrite
+	conflicts []Conflict
+}
+
+func newWriteTracker(order *transformOrder) *writeTracker {
+	return &writeTracker{
+		order:   order,
+		writers: make(map[string]TransformWrite),
+	}
+}
+
+// record notes the attributes t changed in block address, whose rendered text
+// went from before to after.
+func (w *writeTracker) record(t Transform, address, before, after string) {
+	write := TransformWrite{
+		Address: t.Address(),
+		Range:   t.HclBlock().Range(),
+	}
+	for _, path := range changedAttributePaths(before, after) {
+		key := address + "\x00" + path
+		if previous, ok := w.writers[key]; ok && previous.Address != write.Address && !w.order.requires(t, previous.Address) {
+			w.conflicts = append(w.conflicts, Conflict{
+				BlockAddress:  address,
+				AttributePath: path,
+				First:         previous,
+				Second:        write,
+			})
+		}
+		w.writers[key] = write
+	}
+}
+
+// changedAttributePaths returns the sorted paths of the attributes that
+// differ between two renderings of a root block. Attributes of nested blocks
+// are prefixed by the block type and labels, plus an `[index]` when the block
+// type repeats.
+func changedAttributePaths(before, after string) []string {
+	a, b := attributeValues(before), attributeValues(after)
+	var paths []string
+	for path, v := range b {
+		if old, ok := a[path]; !ok || old != v {
+			paths = append(paths, path)
+		}
+	}
+	for path := range a {
+		if _, ok := b[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func attributeValues(rendered string) map[string]string {
+	r := make(map[string]string)
+	if rendered == "" {
+		return r
+	}
+	f, diag := hclwrite.ParseConfig([]byte(rendered), "", hcl.InitialPos)
+	if diag.HasErrors() {
+		return r
+	}
+	body := f.Body()
+	// A root block renders as the block itself, a local value as a bare
+	// attribute.
+	if blocks := body.Blocks(); len(blocks) == 1 && len(body.Attributes()) == 0 {
+		body = blocks[0].Body()
+	}
+	flattenAttributes("", body, r)
+	return r
+}
+
+func flattenAttributes(prefix string, body *hclwrite.Body, r map[string]string) {
+	for name, attr := range body.Attributes() {
+		r[prefix+name] = strings.TrimSpace(string(hclwrite.Format(attr.Expr().BuildTokens(nil).Bytes())))
+	}
+	counts := make(map[string]int)
+	for _, nb := range body.Blocks() {
+		counts[blockPathSegment(nb)]++
+	}
+	indexes := make(map[string]int)
+	for _, nb := range body.Blocks() {
+		segment := blockPathSegment(nb)
+		if counts[segment] > 1 {
+			segment = fmt.Sprintf("%s[%d]", segment, indexes[segment])
+			indexes[blockPathSegment(nb)]++
+		}
+		flattenAttributes(prefix+segment+".", nb.Body(), r)
+	}
+}
+
+func blockPathSegment(b *hclwrite.Block) string {
+	return strings.Join(append([]string{b.Type()}, b.Labels()...), ".")
+}
@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedAttributePaths(t *testing.T) {
+	before := `resource "fake_resource" "this" {
+  name = "a"
+  tags = {}
+  rule {
+    port = 80
+  }
+  rule {
+    port = 443
+  }
+}
+`
+	after := `resource "fake_resource" "this" {
+  name = "a"
+  tags = { env = "prod" }
+  rule {
+    port = 80
+  }
+  rule {
+    port = 8443
+  }
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+`
+	assert.Equal(t, []string{"lifecycle.prevent_destroy", "rule[1].port", "tags"}, changedAttributePaths(before, after))
+	assert.Equal(t, []string{"name", "tags"}, changedAttributePaths("", `resource "fake_resource" "this" {
+  name = "a"
+  tags = {}
+}
+`))
+	assert.Equal(t, []string{"foo"}, changedAttributePaths(`foo = 1`, `foo = 2`))
+}
@@ -0,0 +1,105 @@
+package pkg_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const conflictingTransforms = `transform "update_in_place" first {
+  target_block_address = "resource.fake_resource.this"
+  asstring {
+    tags = "{ owner = \"first\" }"
+  }
+}
+
+transform "update_in_place" second {
+  target_block_address = "resource.fake_resource.this"
+  %s
+  asstring {
+    tags = "{ owner = \"second\" }"
+    name = "\"this\""
+  }
+}
+`
+
+func stubConflictFs(t *testing.T, dependsOn string) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" "this" {
+}
+`,
+		filepath.Join("mptf", "main.mptf.hcl"): fmt.Sprintf(conflictingTransforms, dependsOn),
+	}))
+	t.Cleanup(stub.Reset)
+}
+
+func TestMetaProgrammingTFPlan_OverwrittenAttributeIsAConflict(t *testing.T) {
+	stubConflictFs(t, "")
+	_, plan := runPlan(t)
+	require.NoError(t, plan.Apply())
+
+	conflicts := plan.Conflicts()
+	require.Len(t, conflicts, 1)
+	assert.Equal(t, "resource.fake_resource.this", conflicts[0].BlockAddress)
+	assert.Equal(t, "tags", conflicts[0].AttributePath)
+	assert.Equal(t, "transform.update_in_place.first", conflicts[0].First.Address)
+	assert.Equal(t, "transform.update_in_place.second", conflicts[0].Second.Address)
+	assert.Contains(t, conflicts[0].String(), "main.mptf.hcl:1,1-")
+	content, err := afero.ReadFile(filesystem.Fs, filepath.Join("terraform", "main.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"second"`)
+}
+
+func TestMetaProgrammingTFPlan_DependsOnIsNotAConflict(t *testing.T) {
+	stubConflictFs(t, "depends_on = [transform.update_in_place.first]")
+	_, plan := runPlan(t)
+	require.NoError(t, plan.SetConflictMode(pkg.ConflictModeError))
+	require.NoError(t, plan.Apply())
+	assert.Empty(t, plan.Conflicts())
+}
+
+func TestMetaProgrammingTFPlan_ConflictModeErrorFailsBeforeSaving(t *testing.T) {
+	stubConflictFs(t, "")
+	_, plan := runPlan(t)
+	require.NoError(t, plan.SetConflictMode(pkg.ConflictModeError))
+	err := plan.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resource.fake_resource.this: `tags` written by transform.update_in_place.first")
+	assert.NotContains(t, err.Error(), "`name`")
+	content, err := afero.ReadFile(filesystem.Fs, filepath.Join("terraform", "main.tf"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "tags")
+}
+
+func TestMetaProgrammingTFPlan_ConflictModeOffDoesNotTrack(t *testing.T) {
+	stubConflictFs(t, "")
+	_, plan := runPlan(t)
+	require.NoError(t, plan.SetConflictMode(pkg.ConflictModeOff))
+	require.NoError(t, plan.Apply())
+	assert.Empty(t, plan.Conflicts())
+}
+
+func TestMetaProgrammingTFPlan_ConflictModeIsPerPlan(t *testing.T) {
+	stubConflictFs(t, "")
+	_, failing := runPlan(t)
+	require.NoError(t, failing.SetConflictMode(pkg.ConflictModeError))
+	_, warning := runPlan(t)
+	require.Error(t, failing.Apply())
+	require.NoError(t, warning.Apply())
+	assert.Len(t, warning.Conflicts(), 1)
+}
+
+func TestMetaProgrammingTFPlan_SetConflictModeUnknownModeShouldFail(t *testing.T) {
+	stubConflictFs(t, "")
+	_, plan := runPlan(t)
+	err := plan.SetConflictMode("ignore")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown conflict mode "ignore"`)
+}
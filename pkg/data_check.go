@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/ahmetb/go-linq/v3"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+var _ Data = &DataCheck{}
+
+// DataCheck exposes every `check` block in the target Terraform module as a
+// map keyed by check name. Unlike `import` and `removed`, Terraform requires
+// a name label on `check` blocks, so they keep their native address
+// `check.<name>` instead of a synthetic index.
+//
+// Optional `name` filter narrows the result to a single check block.
+type DataCheck struct {
+	*BaseData
+	*golden.BaseBlock
+
+	ExpectedCheckName string    `hcl:"name,optional"`
+	Result            cty.Value `attribute:"result"`
+}
+
+func (d *DataCheck) Type() string {
+	return "check"
+}
+
+func (d *DataCheck) ExecuteDuringPlan() error {
+	src := d.BaseBlock.Config().(*MetaProgrammingTFConfig).CheckBlocks()
+	var matched []*terraform.RootBlock
+	ds := linq.From(src).Where(func(i interface{}) bool {
+		return len(i.(*terraform.RootBlock).Labels) > 0
+	})
+	if d.ExpectedCheckName != "" {
+		ds = ds.Where(func(i interface{}) bool {
+			return i.(*terraform.RootBlock).Labels[0] == d.ExpectedCheckName
+		})
+	}
+	ds.ToSlice(&matched)
+
+	checkBlocks := make(map[string]cty.Value)
+	for _, block := range matched {
+		checkBlocks[block.Labels[0]] = block.EvalContext()
+	}
+	d.Result = cty.ObjectVal(checkBlocks)
+	return nil
+}
+
+func (d *DataCheck) String() string {
+	data := cty.ObjectVal(map[string]cty.Value{
+		"name":   cty.StringVal(d.ExpectedCheckName),
+		"result": d.Result,
+	})
+	r, err := ctyjson.Marshal(data, data.Type())
+	if err != nil {
+		panic(err.Error())
+	}
+	return string(r)
+}
@@ -0,0 +1,85 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDataCheck_ExecuteDuringPlan(t *testing.T) {
+	tfCode := `
+check "health" {
+  data "http" "this" {
+    url = "https://example.com/health"
+  }
+
+  assert {
+    condition     = data.http.this.status_code == 200
+    error_message = "unhealthy"
+  }
+}
+
+check "certificate" {
+  assert {
+    condition     = true
+    error_message = "expired"
+  }
+}
+`
+	cases := []struct {
+		desc         string
+		name         string
+		expectedKeys []string
+	}{
+		{
+			desc:         "no_filter",
+			expectedKeys: []string{"certificate", "health"},
+		},
+		{
+			desc:         "filter_by_name",
+			name:         "health",
+			expectedKeys: []string{"health"},
+		},
+		{
+			desc:         "no_match",
+			name:         "nonexistent",
+			expectedKeys: []string{},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+				"/main.tf": tfCode,
+			}))
+			defer stub.Reset()
+
+			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+				Dir:    "/",
+				AbsDir: "/",
+			}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
+			require.NoError(t, err)
+
+			data := &pkg.DataCheck{
+				BaseBlock:         golden.NewBaseBlock(cfg, nil),
+				BaseData:          &pkg.BaseData{},
+				ExpectedCheckName: c.name,
+			}
+			require.NoError(t, data.ExecuteDuringPlan())
+
+			values := data.Result.AsValueMap()
+			var keys []string
+			for k, v := range values {
+				keys = append(keys, k)
+				assert.Equal(t, cty.StringVal("check."+k), v.GetAttr("mptf").GetAttr("block_address"))
+			}
+			assert.ElementsMatch(t, c.expectedKeys, keys)
+		})
+	}
+}
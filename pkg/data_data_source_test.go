@@ -80,7 +80,7 @@ data "fake_data" that {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, nil, nil, context.TODO())
+			}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 
 			// Use the config to create a DataSourceData object
@@ -117,7 +117,7 @@ func TestDataSourceData_CustomizedToStringShouldContainsAllFields(t *testing.T)
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 
 	data := &pkg.DataSourceData{
@@ -160,7 +160,7 @@ data "azurerm_public_ip" "pip" {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 
 	data := &pkg.DataSourceData{
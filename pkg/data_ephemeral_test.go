@@ -80,7 +80,7 @@ ephemeral "fake_ephemeral" that {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, nil, nil, context.TODO())
+			}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 
 			data := &pkg.EphemeralData{
@@ -116,7 +116,7 @@ func TestEphemeralData_CustomizedToStringShouldContainsAllFields(t *testing.T) {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 
 	data := &pkg.EphemeralData{
@@ -153,7 +153,7 @@ func TestEphemeralData_RootBlockAddressLookup(t *testing.T) {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 
 	block := cfg.RootBlock("ephemeral.fake_ephemeral.this")
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"github.com/Azure/golden"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+var _ Data = &DataImport{}
+
+// DataImport exposes every `import` block in the target Terraform module as a
+// map keyed by a synthetic, declaration-order index ("0", "1", ...), the same
+// scheme `moved` blocks use. Import blocks have no native label, so the index
+// is the only stable way to address them individually.
+//
+// Each value is the block's EvalContext — `to` and `id` attribute strings plus
+// an `mptf` metadata sub-object.
+type DataImport struct {
+	*BaseData
+	*golden.BaseBlock
+
+	Result cty.Value `attribute:"result"`
+}
+
+func (d *DataImport) Type() string {
+	return "import"
+}
+
+func (d *DataImport) ExecuteDuringPlan() error {
+	src := d.BaseBlock.Config().(*MetaProgrammingTFConfig).ImportBlocks()
+	importBlocks := make(map[string]cty.Value, len(src))
+	for _, block := range src {
+		if len(block.Labels) == 0 {
+			continue
+		}
+		importBlocks[block.Labels[0]] = block.EvalContext()
+	}
+	d.Result = cty.ObjectVal(importBlocks)
+	return nil
+}
+
+func (d *DataImport) String() string {
+	data := cty.ObjectVal(map[string]cty.Value{
+		"result": d.Result,
+	})
+	r, err := ctyjson.Marshal(data, data.Type())
+	if err != nil {
+		panic(err.Error())
+	}
+	return string(r)
+}
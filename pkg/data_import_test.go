@@ -0,0 +1,52 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDataImport_ExecuteDuringPlan(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		"/imports.tf": `
+import {
+  to = azurerm_resource_group.this
+  id = "/subscriptions/0000/resourceGroups/rg"
+}
+`,
+		"/main.tf": `
+import {
+  to = azurerm_storage_account.this
+  id = "/subscriptions/0000/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/sa"
+}
+`,
+	}))
+	defer stub.Reset()
+
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
+	require.NoError(t, err)
+
+	data := &pkg.DataImport{
+		BaseBlock: golden.NewBaseBlock(cfg, nil),
+		BaseData:  &pkg.BaseData{},
+	}
+	require.NoError(t, data.ExecuteDuringPlan())
+
+	values := data.Result.AsValueMap()
+	require.Len(t, values, 2)
+	// Files are visited in name order, so imports.tf comes before main.tf.
+	assert.Equal(t, cty.StringVal("azurerm_resource_group.this"), values["0"].GetAttr("to"))
+	assert.Equal(t, cty.StringVal("azurerm_storage_account.this"), values["1"].GetAttr("to"))
+	assert.Equal(t, cty.StringVal("import.1"), values["1"].GetAttr("mptf").GetAttr("block_address"))
+	assert.NotNil(t, cfg.RootBlock("import.0"))
+}
@@ -69,7 +69,7 @@ func TestDataLocal_QueryLocalBlocks(t *testing.T) {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, nil, nil, context.TODO())
+			}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 
 			// Use the config to create a DataLocal object
@@ -106,7 +106,7 @@ func TestDataLocal_ComplexValues(t *testing.T) {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 
 	data := &pkg.DataLocal{
@@ -15,8 +15,8 @@ var _ Data = &ModuleSourceData{}
 
 // ModuleSourceFetcherFactory is overridable in tests; the default returns a
 // CLI-backed fetcher that runs `terraform get` in a temp directory.
-var ModuleSourceFetcherFactory = func(ctx context.Context) TerraformModuleSourceFetcher {
-	return NewTerraformCliModuleSourceFetcher(ctx)
+var ModuleSourceFetcherFactory = func(ctx context.Context, terraformBinary string) TerraformModuleSourceFetcher {
+	return NewTerraformCliModuleSourceFetcher(ctx, terraformBinary)
 }
 
 // ModuleSourceData fetches the variables and outputs of a Terraform module
@@ -55,7 +55,7 @@ func (d *ModuleSourceData) ExecuteDuringPlan() error {
 			baseDir = cfg.ModuleDir()
 		}
 	}
-	mod, err := ModuleSourceFetcherFactory(d.Context()).Get(d.Source, d.Version, baseDir)
+	mod, err := ModuleSourceFetcherFactory(d.Context(), blockTerraformBinary(d.BaseBlock)).Get(d.Source, d.Version, baseDir)
 	if err != nil {
 		return fmt.Errorf("cannot fetch module source %q version %q: %w", d.Source, d.Version, err)
 	}
@@ -59,7 +59,7 @@ func fakeAvmNamingModule() *tfconfig.Module {
 
 func TestDataModuleSource_PartitionsRequiredAndOptional(t *testing.T) {
 	mod := fakeAvmNamingModule()
-	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context) pkg.TerraformModuleSourceFetcher {
+	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context, terraformBinary string) pkg.TerraformModuleSourceFetcher {
 		return &stubModuleSourceFetcher{mod: mod}
 	}).Stub(&filesystem.Fs, fakeFs(map[string]string{
 		"/main.tf": `resource "azurerm_resource_group" this {
@@ -109,7 +109,7 @@ transform "update_in_place" out_keys {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	require.NoError(t, cfg.Init(hclBlocks))
 	require.NoError(t, cfg.RunPrePlan())
@@ -139,7 +139,7 @@ func TestDataModuleSource_StringJSONShape(t *testing.T) {
 			"o": {Name: "o", Description: "out"},
 		},
 	}
-	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context) pkg.TerraformModuleSourceFetcher {
+	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context, terraformBinary string) pkg.TerraformModuleSourceFetcher {
 		return &stubModuleSourceFetcher{mod: mod}
 	})
 	defer stub.Reset()
@@ -198,7 +198,7 @@ func TestDataModuleSource_EmptyModule(t *testing.T) {
 		Variables: map[string]*tfconfig.Variable{},
 		Outputs:   map[string]*tfconfig.Output{},
 	}
-	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context) pkg.TerraformModuleSourceFetcher {
+	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context, terraformBinary string) pkg.TerraformModuleSourceFetcher {
 		return &stubModuleSourceFetcher{mod: mod}
 	})
 	defer stub.Reset()
@@ -228,7 +228,7 @@ func TestDataModuleSource_BaseDirAutoDefaultsToModuleDir(t *testing.T) {
 		},
 		Outputs: map[string]*tfconfig.Output{},
 	}}
-	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context) pkg.TerraformModuleSourceFetcher {
+	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context, terraformBinary string) pkg.TerraformModuleSourceFetcher {
 		return fetcher
 	}).Stub(&filesystem.Fs, fakeFs(map[string]string{
 		"/main.tf": `resource "azurerm_resource_group" this {
@@ -245,7 +245,7 @@ data "module_source" "local" {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	require.NoError(t, cfg.Init(hclBlocks))
 	require.NoError(t, cfg.RunPrePlan())
@@ -267,7 +267,7 @@ func TestDataModuleSource_BaseDirExplicitOverride(t *testing.T) {
 		Variables: map[string]*tfconfig.Variable{},
 		Outputs:   map[string]*tfconfig.Output{},
 	}}
-	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context) pkg.TerraformModuleSourceFetcher {
+	stub := gostub.Stub(&pkg.ModuleSourceFetcherFactory, func(ctx context.Context, terraformBinary string) pkg.TerraformModuleSourceFetcher {
 		return fetcher
 	}).Stub(&filesystem.Fs, fakeFs(map[string]string{
 		"/main.tf": `resource "azurerm_resource_group" this {
@@ -285,7 +285,7 @@ data "module_source" "local" {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	require.NoError(t, cfg.Init(hclBlocks))
 	require.NoError(t, cfg.RunPrePlan())
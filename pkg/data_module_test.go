@@ -74,7 +74,7 @@ module "first" {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, nil, nil, context.TODO())
+			}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 
 			data := &pkg.DataModule{
@@ -76,7 +76,7 @@ moved {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, nil, nil, context.TODO())
+			}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 
 			data := &pkg.DataMoved{
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"strings"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/ahmetb/go-linq/v3"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+var _ Data = &DataProvider{}
+
+// DataProvider exposes every `provider` configuration block in the target
+// Terraform module as a map keyed by the block address without its
+// `provider.` prefix: `azurerm` for a default configuration and
+// `azurerm.secondary` for one declared with `alias = "secondary"`.
+//
+// Optional `provider_name` and `alias` filters narrow the result.
+type DataProvider struct {
+	*BaseData
+	*golden.BaseBlock
+
+	ProviderName string    `hcl:"provider_name,optional"`
+	Alias        string    `hcl:"alias,optional"`
+	Result       cty.Value `attribute:"result"`
+}
+
+func (d *DataProvider) Type() string {
+	return "provider"
+}
+
+func (d *DataProvider) ExecuteDuringPlan() error {
+	src := d.BaseBlock.Config().(*MetaProgrammingTFConfig).ProviderBlocks()
+	var matched []*terraform.RootBlock
+	ds := linq.From(src)
+	if d.ProviderName != "" {
+		ds = ds.Where(func(i interface{}) bool {
+			return i.(*terraform.RootBlock).Labels[0] == d.ProviderName
+		})
+	}
+	if d.Alias != "" {
+		ds = ds.Where(func(i interface{}) bool {
+			return terraform.ProviderAlias(i.(*terraform.RootBlock).Block) == d.Alias
+		})
+	}
+	ds.ToSlice(&matched)
+
+	providerBlocks := make(map[string]cty.Value)
+	for _, block := range matched {
+		providerBlocks[strings.TrimPrefix(block.Address, "provider.")] = block.EvalContext()
+	}
+	d.Result = cty.ObjectVal(providerBlocks)
+	return nil
+}
+
+func (d *DataProvider) String() string {
+	data := cty.ObjectVal(map[string]cty.Value{
+		"provider_name": cty.StringVal(d.ProviderName),
+		"alias":         cty.StringVal(d.Alias),
+		"result":        d.Result,
+	})
+	r, err := ctyjson.Marshal(data, data.Type())
+	if err != nil {
+		panic(err.Error())
+	}
+	return string(r)
+}
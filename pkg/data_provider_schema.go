@@ -14,8 +14,8 @@ import (
 )
 
 var _ Data = &ProviderSchemaData{}
-var SchemaRetrieverFactory = func(ctx context.Context) TerraformProviderSchemaRetriever {
-	return NewTerraformCliProviderSchemaRetriever(ctx)
+var SchemaRetrieverFactory = func(ctx context.Context, terraformBinary string) TerraformProviderSchemaRetriever {
+	return NewTerraformCliProviderSchemaRetriever(ctx, terraformBinary)
 }
 
 type ProviderSchemaData struct {
@@ -37,7 +37,7 @@ func (r *ProviderSchemaData) Type() string {
 }
 
 func (r *ProviderSchemaData) ExecuteDuringPlan() error {
-	schemas, err := SchemaRetrieverFactory(r.Context()).Get(r.Source, r.Version)
+	schemas, err := SchemaRetrieverFactory(r.Context(), blockTerraformBinary(r.BaseBlock)).Get(r.Source, r.Version)
 	if err != nil {
 		return fmt.Errorf("cannot read `terraform prviders schema` for source %s with version %s: %+v", r.Source, r.Version, err)
 	}
@@ -986,7 +986,7 @@ func TestDataProviderSchema_mockSchemaRetriever(t *testing.T) {
       }
     }
 `
-	stub := gostub.Stub(&pkg.SchemaRetrieverFactory, func(ctx context.Context) pkg.TerraformProviderSchemaRetriever {
+	stub := gostub.Stub(&pkg.SchemaRetrieverFactory, func(ctx context.Context, terraformBinary string) pkg.TerraformProviderSchemaRetriever {
 		return mockProviderSchemaRetriever{t: t, jsonSchema: localSchema}
 	}).Stub(&filesystem.Fs, fakeFs(map[string]string{
 		filepath.Join("terraform", "main.tf"): `resource "azurerm_app_configuration" this {
@@ -1028,7 +1028,7 @@ transform "update_in_place" tags {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "terraform",
 		AbsDir: "terraform",
-	}, nil, hclBlocks, nil, context.TODO())
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 	require.NoError(t, err)
@@ -1123,7 +1123,7 @@ func TestDataProviderSchema_SortedAttributeLists(t *testing.T) {
       }
     }
 `
-	stub := gostub.Stub(&pkg.SchemaRetrieverFactory, func(ctx context.Context) pkg.TerraformProviderSchemaRetriever {
+	stub := gostub.Stub(&pkg.SchemaRetrieverFactory, func(ctx context.Context, terraformBinary string) pkg.TerraformProviderSchemaRetriever {
 		return mockProviderSchemaRetriever{t: t, jsonSchema: syntheticSchema}
 	}).Stub(&filesystem.Fs, fakeFs(map[string]string{
 		"/main.tf": `resource "azurerm_resource_group" this {
@@ -1189,7 +1189,7 @@ transform "update_in_place" data_sources_marker {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	require.NoError(t, cfg.Init(hclBlocks))
 	require.NoError(t, cfg.RunPrePlan())
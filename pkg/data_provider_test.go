@@ -0,0 +1,99 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDataProvider_ExecuteDuringPlan(t *testing.T) {
+	tfCode := `
+provider "azurerm" {
+  features {}
+}
+
+provider "azurerm" {
+  alias           = "secondary"
+  subscription_id = "00000000-0000-0000-0000-000000000000"
+  features {}
+}
+
+provider "random" {}
+`
+	cases := []struct {
+		desc            string
+		providerName    string
+		alias           string
+		expectedKeys    []string
+		expectedAddress map[string]string
+	}{
+		{
+			desc:         "no_filter",
+			expectedKeys: []string{"azurerm", "azurerm.secondary", "random"},
+			expectedAddress: map[string]string{
+				"azurerm":           "provider.azurerm",
+				"azurerm.secondary": "provider.azurerm.secondary",
+				"random":            "provider.random",
+			},
+		},
+		{
+			desc:         "filter_by_provider_name",
+			providerName: "azurerm",
+			expectedKeys: []string{"azurerm", "azurerm.secondary"},
+		},
+		{
+			desc:         "filter_by_alias",
+			providerName: "azurerm",
+			alias:        "secondary",
+			expectedKeys: []string{"azurerm.secondary"},
+		},
+		{
+			desc:         "no_match",
+			providerName: "aws",
+			expectedKeys: []string{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+				"/main.tf": tfCode,
+			}))
+			defer stub.Reset()
+
+			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+				Dir:    "/",
+				AbsDir: "/",
+			}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
+			require.NoError(t, err)
+
+			data := &pkg.DataProvider{
+				BaseBlock:    golden.NewBaseBlock(cfg, nil),
+				BaseData:     &pkg.BaseData{},
+				ProviderName: c.providerName,
+				Alias:        c.alias,
+			}
+			err = data.ExecuteDuringPlan()
+			require.NoError(t, err)
+
+			result := data.Result.AsValueMap()
+			var keys []string
+			for k := range result {
+				keys = append(keys, k)
+			}
+			assert.ElementsMatch(t, c.expectedKeys, keys)
+			for key, address := range c.expectedAddress {
+				mptf := result[key].GetAttr("mptf")
+				assert.Equal(t, cty.StringVal(address), mptf.GetAttr("block_address"))
+				assert.NotNil(t, cfg.RootBlock(address))
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestDataRemoved_ExecuteDuringPlan(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		"/main.tf": `
+removed {
+  from = azurerm_resource_group.legacy
+
+  lifecycle {
+    destroy = false
+  }
+}
+
+removed {
+  from = module.legacy
+}
+`,
+	}))
+	defer stub.Reset()
+
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
+	require.NoError(t, err)
+
+	data := &pkg.DataRemoved{
+		BaseBlock: golden.NewBaseBlock(cfg, nil),
+		BaseData:  &pkg.BaseData{},
+	}
+	require.NoError(t, data.ExecuteDuringPlan())
+
+	values := data.Result.AsValueMap()
+	require.Len(t, values, 2)
+	assert.Equal(t, cty.StringVal("azurerm_resource_group.legacy"), values["0"].GetAttr("from"))
+	assert.Equal(t, cty.StringVal("module.legacy"), values["1"].GetAttr("from"))
+	assert.Equal(t, cty.StringVal("removed.0"), values["0"].GetAttr("mptf").GetAttr("block_address"))
+}
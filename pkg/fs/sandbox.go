@@ -0,0 +1,185 @@
+package fs
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+var _ afero.Fs = &Sandbox{}
+
+// Sandbox is a copy-on-write filesystem layered over a base filesystem. Reads
+// fall through to the base until a file is written; every write lands in an
+// in-memory layer so the base is never modified. The sandbox remembers each
+// path opened for writing, which lets callers run the whole transform
+// pipeline (including Module.SaveToDisk) and then inspect exactly which files
+// would have changed.
+type Sandbox struct {
+	afero.Fs
+	base    afero.Fs
+	mu      sync.Mutex
+	written map[string]struct{}
+}
+
+// FileChange describes the content of one file before and after a sandboxed
+// run. Before is nil when the file did not exist in the base filesystem.
+type FileChange struct {
+	Path   string
+	Before []byte
+	After  []byte
+}
+
+func NewSandbox(base afero.Fs) *Sandbox {
+	return &Sandbox{
+		Fs:      afero.NewCopyOnWriteFs(base, afero.NewMemMapFs()),
+		base:    base,
+		written: make(map[string]struct{}),
+	}
+}
+
+func (s *Sandbox) Create(name string) (afero.File, error) {
+	s.record(name)
+	return s.Fs.Create(name)
+}
+
+func (s *Sandbox) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0 {
+		s.record(name)
+	}
+	return s.Fs.OpenFile(name, flag, perm)
+}
+
+func (s *Sandbox) Name() string {
+	return "Sandbox"
+}
+
+func (s *Sandbox) record(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written[name] = struct{}{}
+}
+
+// Changes returns every written file whose content differs from the base
+// filesystem, sorted by path.
+func (s *Sandbox) Changes() ([]FileChange, error) {
+	s.mu.Lock()
+	paths := make([]string, 0, len(s.written))
+	for p := range s.written {
+		paths = append(paths, p)
+	}
+	s.mu.Unlock()
+	sort.Strings(paths)
+
+	var changes []FileChange
+	for _, p := range paths {
+		after, err := afero.ReadFile(s.Fs, p)
+		if err != nil {
+			return nil, err
+		}
+		var before []byte
+		exist, err := afero.Exists(s.base, p)
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			if before, err = afero.ReadFile(s.base, p); err != nil {
+				return nil, err
+			}
+		}
+		if exist && bytes.Equal(before, after) {
+			continue
+		}
+		changes = append(changes, FileChange{
+			Path:   p,
+			Before: before,
+			After:  after,
+		})
+	}
+	return changes, nil
+}
+
+// Commit writes every change onto the base filesystem. Files that already
+// existed keep their permission. When a write fails, the files written so far
+// are put back as they were, so the base is either fully updated or untouched.
+func (s *Sandbox) Commit() error {
+	changes, err := s.Changes()
+	if err != nil {
+		return err
+	}
+	for i, c := range changes {
+		if err = s.write(c.Path, c.After); err != nil {
+			err = fmt.Errorf("cannot write %s: %+v", c.Path, err)
+			for _, written := range changes[:i] {
+				if rollbackErr := s.rollback(written); rollbackErr != nil {
+					err = multierror.Append(err, rollbackErr)
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Sandbox) write(path string, content []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := s.base.Stat(path); err == nil {
+		mode = info.Mode()
+	} else if err = s.base.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return afero.WriteFile(s.base, path, content, mode)
+}
+
+func (s *Sandbox) rollback(c FileChange) error {
+	if c.Before == nil {
+		if err := s.base.Remove(c.Path); err != nil {
+			return fmt.Errorf("cannot roll back %s: %+v", c.Path, err)
+		}
+		return nil
+	}
+	if err := s.write(c.Path, c.Before); err != nil {
+		return fmt.Errorf("cannot roll back %s: %+v", c.Path, err)
+	}
+	return nil
+}
+
+// UnifiedDiff renders the change as a unified diff with three lines of
+// context, labelled `a/<name>` and `b/<name>` like `git diff`. A created file
+// is diffed against `/dev/null`.
+func (c FileChange) UnifiedDiff(name string) (string, error) {
+	from := "a/" + name
+	if c.Before == nil {
+		from = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(c.Before),
+		B:        splitLines(c.After),
+		FromFile: from,
+		ToFile:   "b/" + name,
+		Context:  3,
+	})
+}
+
+// splitLines splits content into newline-terminated lines. Unlike
+// difflib.SplitLines it does not emit a phantom empty line after the final
+// newline, and it terminates a last line that has no newline so the rendered
+// diff stays well-formed.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
@@ -0,0 +1,127 @@
+package fs_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandbox_WritesNeverReachBase(t *testing.T) {
+	base := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(base, "/tf/main.tf", []byte("a\nb\n"), 0644))
+	require.NoError(t, afero.WriteFile(base, "/tf/same.tf", []byte("same\n"), 0644))
+	sandbox := filesystem.NewSandbox(base)
+
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/main.tf", []byte("a\nc\n"), 0644))
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/same.tf", []byte("same\n"), 0644))
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/new.tf", []byte("new\n"), 0644))
+
+	content, err := afero.ReadFile(base, "/tf/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(content))
+	exists, err := afero.Exists(base, "/tf/new.tf")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	changes, err := sandbox.Changes()
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	assert.Equal(t, "/tf/main.tf", changes[0].Path)
+	assert.Equal(t, "a\nb\n", string(changes[0].Before))
+	assert.Equal(t, "a\nc\n", string(changes[0].After))
+	assert.Equal(t, "/tf/new.tf", changes[1].Path)
+	assert.Nil(t, changes[1].Before)
+}
+
+func TestFileChange_UnifiedDiff(t *testing.T) {
+	cases := []struct {
+		desc     string
+		change   filesystem.FileChange
+		expected string
+	}{
+		{
+			desc: "modified_file",
+			change: filesystem.FileChange{
+				Before: []byte("a\nb\n"),
+				After:  []byte("a\nc\n"),
+			},
+			expected: "--- a/main.tf\n+++ b/main.tf\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+		{
+			desc: "created_file",
+			change: filesystem.FileChange{
+				After: []byte("new"),
+			},
+			expected: "--- /dev/null\n+++ b/main.tf\n@@ -0,0 +1 @@\n+new\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			diff, err := c.change.UnifiedDiff("main.tf")
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, diff)
+		})
+	}
+}
+
+func TestSandbox_CommitWritesChangesToBase(t *testing.T) {
+	base := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(base, "/tf/main.tf", []byte("a\n"), 0600))
+	sandbox := filesystem.NewSandbox(base)
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/main.tf", []byte("b\n"), 0644))
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/new.tf", []byte("new\n"), 0644))
+
+	require.NoError(t, sandbox.Commit())
+
+	content, err := afero.ReadFile(base, "/tf/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, "b\n", string(content))
+	info, err := base.Stat("/tf/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	content, err = afero.ReadFile(base, "/tf/new.tf")
+	require.NoError(t, err)
+	assert.Equal(t, "new\n", string(content))
+}
+
+// failingFs refuses to write failPath.
+type failingFs struct {
+	afero.Fs
+	failPath string
+}
+
+func (f failingFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	if name == f.failPath && flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return nil, errors.New("disk full")
+	}
+	return f.Fs.OpenFile(name, flag, perm)
+}
+
+func TestSandbox_CommitRollsBackWhenAWriteFails(t *testing.T) {
+	base := failingFs{Fs: afero.NewMemMapFs(), failPath: "/tf/z.tf"}
+	require.NoError(t, afero.WriteFile(base.Fs, "/tf/main.tf", []byte("a\n"), 0644))
+	require.NoError(t, afero.WriteFile(base.Fs, "/tf/z.tf", []byte("z\n"), 0644))
+	sandbox := filesystem.NewSandbox(base)
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/main.tf", []byte("b\n"), 0644))
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/new.tf", []byte("new\n"), 0644))
+	require.NoError(t, afero.WriteFile(sandbox, "/tf/z.tf", []byte("changed\n"), 0644))
+
+	err := sandbox.Commit()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+
+	content, err := afero.ReadFile(base, "/tf/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, "a\n", string(content))
+	exists, err := afero.Exists(base, "/tf/new.tf")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	content, err = afero.ReadFile(base, "/tf/z.tf")
+	require.NoError(t, err)
+	assert.Equal(t, "z\n", string(content))
+}
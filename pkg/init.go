@@ -11,32 +11,52 @@ func init() {
 	})
 	registerData()
 	registerTransform()
+	registerBlock(new(RuleBlock))
+}
+
+// registeredBlocks holds a zero value of every `transform`, `data` and `rule`
+// block type, in registration order.
+var registeredBlocks []golden.Block
+
+func registerBlock(b golden.Block) {
+	golden.RegisterBlock(b)
+	registeredBlocks = append(registeredBlocks, b)
+}
+
+// RegisteredBlocks returns a zero value of every `transform`, `data` and
+// `rule` block type, for tools that inspect the DSL schema.
+func RegisteredBlocks() []golden.Block {
+	return append([]golden.Block{}, registeredBlocks...)
 }
 
 func registerTransform() {
-	golden.RegisterBlock(new(UpdateInPlaceTransform))
-	golden.RegisterBlock(new(NewBlockTransform))
-	golden.RegisterBlock(new(RemoveBlockTransform))
-	golden.RegisterBlock(new(RemoveBlockContentBlockTransform))
-	golden.RegisterBlock(new(RenameAttributeOrNestedBlockTransform))
-	golden.RegisterBlock(new(RegexReplaceExpressionTransform))
-	golden.RegisterBlock(new(AppendBlockBodyTransform))
-	golden.RegisterBlock(new(EnsureLocalTransform))
-	golden.RegisterBlock(new(MoveBlockTransform))
-	golden.RegisterBlock(new(ReorderAttributesTransform))
-	golden.RegisterBlock(new(SortBlocksInFileTransform))
+	registerBlock(new(UpdateInPlaceTransform))
+	registerBlock(new(NewBlockTransform))
+	registerBlock(new(RemoveBlockTransform))
+	registerBlock(new(RemoveBlockContentBlockTransform))
+	registerBlock(new(RenameAttributeOrNestedBlockTransform))
+	registerBlock(new(RegexReplaceExpressionTransform))
+	registerBlock(new(AppendBlockBodyTransform))
+	registerBlock(new(EnsureLocalTransform))
+	registerBlock(new(MoveBlockTransform))
+	registerBlock(new(ReorderAttributesTransform))
+	registerBlock(new(SortBlocksInFileTransform))
 }
 
 func registerData() {
-	golden.RegisterBlock(new(ResourceData))
-	golden.RegisterBlock(new(ProviderSchemaData))
-	golden.RegisterBlock(new(TerraformData))
-	golden.RegisterBlock(new(DataSourceData))
-	golden.RegisterBlock(new(EphemeralData))
-	golden.RegisterBlock(new(DataVariable))
-	golden.RegisterBlock(new(DataOutput))
-	golden.RegisterBlock(new(DataLocal))
-	golden.RegisterBlock(new(DataModule))
-	golden.RegisterBlock(new(DataMoved))
-	golden.RegisterBlock(new(ModuleSourceData))
+	registerBlock(new(ResourceData))
+	registerBlock(new(ProviderSchemaData))
+	registerBlock(new(TerraformData))
+	registerBlock(new(DataSourceData))
+	registerBlock(new(EphemeralData))
+	registerBlock(new(DataVariable))
+	registerBlock(new(DataOutput))
+	registerBlock(new(DataLocal))
+	registerBlock(new(DataModule))
+	registerBlock(new(DataMoved))
+	registerBlock(new(DataProvider))
+	registerBlock(new(DataImport))
+	registerBlock(new(DataRemoved))
+	registerBlock(new(DataCheck))
+	registerBlock(new(ModuleSourceData))
 }
@@ -0,0 +1,242 @@
+package lsp
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/mapotf/pkg"
+	"github.com/Azure/mapotf/pkg/terraform"
+)
+
+var (
+	// blockTypePrefixRegex matches a root block header up to its type label,
+	// like `transform "upd`.
+	blockTypePrefixRegex = regexp.MustCompile(`^\s*(transform|data)\s+"?([\w-]*)$`)
+	bareWordRegex        = regexp.MustCompile(`^\s*[\w-]*$`)
+	// traversalPrefixRegex matches a reference being typed, like
+	// `data.resource.all.re`: group 1 is everything before the last dot.
+	traversalPrefixRegex = regexp.MustCompile(`(?:^|[^\w.\-"])([A-Za-z_][\w-]*(?:\.[\w-]+|\[[^\]]*\])*)\.([\w-]*)$`)
+	indexRegex           = regexp.MustCompile(`\[[^\]]*\]`)
+)
+
+var rootKeywords = []string{"data", "locals", "rule", "transform", "variable"}
+
+// Keys of the `mptf` object of Terraform blocks matched by data blocks.
+var (
+	mptfKeys       = []string{"attribute_sources", "block_address", "block_labels", "block_type", "module", "override_files", "range", "terraform_address"}
+	mptfModuleKeys = []string{"abs_dir", "dir", "git_hash", "key", "keys", "source", "version"}
+	mptfRangeKeys  = []string{"end_column", "end_line", "file_name", "start_column", "start_line"}
+)
+
+// resultBlockTypes maps the data blocks whose `result` is keyed by Terraform
+// block type, then name, to the type of those Terraform blocks.
+var resultBlockTypes = map[string]string{
+	"resource":  "resource",
+	"data":      "data",
+	"ephemeral": "ephemeral",
+}
+
+func (s *Server) completion(path, text string, pos Position) []CompletionItem {
+	offset := offsetAt(text, pos)
+	frames, inString := scope(text, offset)
+	linePrefix := text[lineStart(text, offset):offset]
+	if len(frames) == 0 {
+		if m := blockTypePrefixRegex.FindStringSubmatch(linePrefix); m != nil {
+			return blockTypeItems(m[1])
+		}
+		if bareWordRegex.MatchString(linePrefix) {
+			return keywordItems(rootKeywords)
+		}
+		return []CompletionItem{}
+	}
+	if inString {
+		return []CompletionItem{}
+	}
+	if m := traversalPrefixRegex.FindStringSubmatch(linePrefix); m != nil {
+		parts := strings.Split(indexRegex.ReplaceAllString(m[1], ""), ".")
+		return s.traversalItems(filepath.Dir(path), parts)
+	}
+	if !bareWordRegex.MatchString(linePrefix) {
+		return []CompletionItem{}
+	}
+	schema := schemaAt(frames)
+	if schema == nil {
+		return []CompletionItem{}
+	}
+	var items []CompletionItem
+	for _, a := range schema.Arguments {
+		items = append(items, CompletionItem{
+			Label:  a.Name,
+			Kind:   KindProperty,
+			Detail: a.detail(),
+		})
+	}
+	for _, name := range schema.blockNames() {
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          KindStruct,
+			Detail:        "block",
+			Documentation: markdown(schema.Blocks[name].markdown()),
+		})
+	}
+	return items
+}
+
+func blockTypeItems(kind string) []CompletionItem {
+	items := []CompletionItem{}
+	for _, name := range blockTypes(kind) {
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          KindStruct,
+			Detail:        kind,
+			Documentation: markdown(schemas[kind][name].markdown()),
+		})
+	}
+	return items
+}
+
+func keywordItems(words []string) []CompletionItem {
+	items := []CompletionItem{}
+	for _, w := range words {
+		items = append(items, CompletionItem{Label: w, Kind: KindKeyword})
+	}
+	return items
+}
+
+func fieldItems(names []string, detail string) []CompletionItem {
+	items := []CompletionItem{}
+	for _, n := range names {
+		items = append(items, CompletionItem{Label: n, Kind: KindField, Detail: detail})
+	}
+	return items
+}
+
+// traversalItems completes the next step of the reference parts, like the
+// data block types after `data.` or the Terraform resource types after
+// `data.resource.all.result.`.
+func (s *Server) traversalItems(dir string, parts []string) []CompletionItem {
+	if i := lastIndex(parts, "mptf"); i >= 0 {
+		switch len(parts) - i {
+		case 1:
+			return fieldItems(mptfKeys, "mptf")
+		case 2:
+			if parts[i+1] == "module" {
+				return fieldItems(mptfModuleKeys, "mptf.module")
+			}
+			if parts[i+1] == "range" {
+				return fieldItems(mptfRangeKeys, "mptf.range")
+			}
+		}
+		return []CompletionItem{}
+	}
+	switch parts[0] {
+	case "var":
+		if len(parts) == 1 {
+			return fieldItems(s.declaredLabels(dir, "variable", ""), "variable")
+		}
+	case "local":
+		if len(parts) == 1 {
+			return fieldItems(s.declaredLocals(dir), "local")
+		}
+	case "each":
+		if len(parts) == 1 {
+			return fieldItems([]string{"key", "value"}, "each")
+		}
+	case "data", "transform":
+		kind := parts[0]
+		switch len(parts) {
+		case 1:
+			return blockTypeItems(kind)
+		case 2:
+			return fieldItems(s.declaredLabels(dir, kind, parts[1]), kind+" "+parts[1])
+		case 3:
+			schema := schemas[kind][parts[1]]
+			if schema == nil {
+				return []CompletionItem{}
+			}
+			items := []CompletionItem{}
+			for _, a := range schema.Attributes {
+				items = append(items, CompletionItem{Label: a.Name, Kind: KindField, Detail: a.Type})
+			}
+			return items
+		}
+		if tfType, ok := resultBlockTypes[parts[1]]; ok && kind == "data" && parts[3] == "result" {
+			return s.resultItems(tfType, parts[4:])
+		}
+	}
+	return []CompletionItem{}
+}
+
+// resultItems completes inside the `result` of a data block matching
+// Terraform blocks of tfType: block types, then names, then the attributes
+// and nested blocks of the block.
+func (s *Server) resultItems(tfType string, parts []string) []CompletionItem {
+	blocks := s.terraformBlocks(tfType)
+	seen := make(map[string]struct{})
+	for _, b := range blocks {
+		if len(b.Labels) != 2 {
+			continue
+		}
+		switch len(parts) {
+		case 0:
+			seen[b.Labels[0]] = struct{}{}
+		case 1:
+			if b.Labels[0] == parts[0] {
+				seen[b.Labels[1]] = struct{}{}
+			}
+		case 2:
+			if b.Labels[0] != parts[0] || b.Labels[1] != parts[1] {
+				continue
+			}
+			seen["mptf"] = struct{}{}
+			for name := range b.Attributes {
+				seen[name] = struct{}{}
+			}
+			for blockType := range b.NestedBlocks {
+				seen[blockType] = struct{}{}
+			}
+		}
+	}
+	return fieldItems(sortedKeys(seen), tfType)
+}
+
+// terraformBlocks returns the root blocks of type tfType in the Terraform
+// module of the server.
+func (s *Server) terraformBlocks(tfType string) []*terraform.RootBlock {
+	moduleRef, err := pkg.NewTerraformRootModuleRef(s.tfDir)
+	if err != nil {
+		return nil
+	}
+	module, err := terraform.LoadModule(terraform.ModuleRef{
+		Dir:    moduleRef.Dir,
+		AbsDir: moduleRef.AbsDir,
+	}, s.tfOptions.LoadOptions)
+	if err != nil {
+		return nil
+	}
+	var r []*terraform.RootBlock
+	for _, b := range module.Blocks() {
+		if b.Type == tfType {
+			r = append(r, b)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Address < r[j].Address
+	})
+	return r
+}
+
+func lastIndex(parts []string, s string) int {
+	for i := len(parts) - 1; i >= 0; i-- {
+		if parts[i] == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func markdown(value string) *MarkupContent {
+	return &MarkupContent{Kind: "markdown", Value: value}
+}
@@ -0,0 +1,123 @@
+package lsp
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var targetBlockAddressRegex = regexp.MustCompile(`^\s*target_block_address\s*=`)
+
+// definition jumps from the `target_block_address` of a transform to the
+// Terraform blocks it targets. A string literal is resolved as is, any other
+// expression through the values the last analysis evaluated for every
+// instance of the transform.
+func (s *Server) definition(ctx context.Context, path, text string, pos Position) []Location {
+	locations := []Location{}
+	offset := offsetAt(text, pos)
+	frames, _ := scope(text, offset)
+	root, nested, ok := rootBlock(frames)
+	if !ok || len(nested) > 0 || len(root) != 3 || root[0] != "transform" {
+		return locations
+	}
+	start := lineStart(text, offset)
+	line := text[start:lineEnd(text, offset)]
+	m := targetBlockAddressRegex.FindStringIndex(line)
+	if m == nil {
+		return locations
+	}
+	dir := filepath.Dir(path)
+	a := s.analyses[dir]
+	if a == nil {
+		s.analyzeDir(ctx, dir)
+		if a = s.analyses[dir]; a == nil {
+			return locations
+		}
+	}
+	var addresses []string
+	if literal, err := strconv.Unquote(strings.TrimSpace(line[m[1]:])); err == nil && !strings.Contains(literal, "${") {
+		addresses = []string{literal}
+	} else {
+		addresses = targetBlockAddresses(a.cfg.EvalContext().Variables["transform"], root[1], root[2])
+	}
+	for _, address := range addresses {
+		if b := a.cfg.RootBlock(address); b != nil {
+			locations = append(locations, blockLocation(a.moduleRef.AbsDir, b))
+		}
+	}
+	return locations
+}
+
+// targetBlockAddresses returns the `target_block_address` of every instance
+// of the `transform "<blockType>" "<name>"` in transforms.
+func targetBlockAddresses(transforms cty.Value, blockType, name string) []string {
+	v, ok := getAttr(transforms, blockType)
+	if !ok {
+		return nil
+	}
+	if v, ok = getAttr(v, name); !ok {
+		return nil
+	}
+	if address, ok := stringAttr(v, "target_block_address"); ok {
+		return []string{address}
+	}
+	if !v.CanIterateElements() {
+		return nil
+	}
+	var r []string
+	for it := v.ElementIterator(); it.Next(); {
+		_, instance := it.Element()
+		if address, ok := stringAttr(instance, "target_block_address"); ok {
+			r = append(r, address)
+		}
+	}
+	return r
+}
+
+func getAttr(v cty.Value, name string) (cty.Value, bool) {
+	if !v.IsKnown() || v.IsNull() {
+		return cty.NilVal, false
+	}
+	t := v.Type()
+	if t.IsObjectType() && t.HasAttribute(name) {
+		return v.GetAttr(name), true
+	}
+	if t.IsMapType() && v.HasIndex(cty.StringVal(name)).True() {
+		return v.Index(cty.StringVal(name)), true
+	}
+	return cty.NilVal, false
+}
+
+func stringAttr(v cty.Value, name string) (string, bool) {
+	attr, ok := getAttr(v, name)
+	if !ok || !attr.IsKnown() || attr.IsNull() || attr.Type() != cty.String {
+		return "", false
+	}
+	return attr.AsString(), true
+}
+
+func blockLocation(moduleDir string, b *terraform.RootBlock) Location {
+	r := b.DefRange()
+	fileName := r.Filename
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(moduleDir, fileName)
+	}
+	rng := Range{
+		Start: Position{Line: r.Start.Line - 1, Character: r.Start.Column - 1},
+		End:   Position{Line: r.End.Line - 1, Character: r.End.Column - 1},
+	}
+	if content, err := afero.ReadFile(filesystem.Fs, fileName); err == nil {
+		rng = Range{
+			Start: positionAt(string(content), r.Start.Byte),
+			End:   positionAt(string(content), r.End.Byte),
+		}
+	}
+	return Location{URI: pathToURI(fileName), Range: rng}
+}
@@ -0,0 +1,339 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/spf13/afero"
+)
+
+// analysis is an mptf dir planned against the Terraform module.
+type analysis struct {
+	moduleRef *pkg.TerraformModuleRef
+	cfg       *pkg.MetaProgrammingTFConfig
+}
+
+// analyzeDir loads, plans and decodes the mptf dir with the open documents in
+// place of the files on disk, then publishes the diagnostics of every open
+// document of the dir.
+func (s *Server) analyzeDir(ctx context.Context, dir string) {
+	a, errs := s.analyze(ctx, dir)
+	if a != nil {
+		s.analyses[dir] = a
+	}
+	for _, path := range s.mptfDocuments(dir) {
+		var diagnostics []Diagnostic
+		for _, err := range errs {
+			if d, ok := diagnosticFor(path, s.documents[path], err); ok {
+				diagnostics = append(diagnostics, d)
+			}
+		}
+		s.diagnostics[path] = diagnostics
+		s.publish(path, diagnostics)
+	}
+}
+
+func (s *Server) analyze(ctx context.Context, dir string) (*analysis, []error) {
+	overlay := filesystem.NewSandbox(filesystem.Fs)
+	for _, path := range s.mptfDocuments(dir) {
+		if err := afero.WriteFile(overlay, path, []byte(s.documents[path]), 0644); err != nil {
+			return nil, []error{err}
+		}
+	}
+	realFs := filesystem.Fs
+	filesystem.Fs = overlay
+	defer func() {
+		filesystem.Fs = realFs
+	}()
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, dir)
+	if err != nil {
+		return nil, flattenErrors(err)
+	}
+	moduleRef, err := pkg.NewTerraformRootModuleRef(s.tfDir)
+	if err != nil {
+		return nil, []error{fmt.Errorf("cannot load the Terraform module in %s: %+v", s.tfDir, err)}
+	}
+	cfg, err := pkg.NewMetaProgrammingTFConfig(moduleRef, nil, hclBlocks, unsetVariables(hclBlocks), s.tfOptions, ctx)
+	if err != nil {
+		return nil, flattenErrors(err)
+	}
+	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+	if err != nil {
+		return nil, planDiagnostics(cfg, flattenErrors(err))
+	}
+	var errs []error
+	for _, t := range plan.Transforms {
+		if err = golden.Decode(t); err != nil {
+			subject := t.HclBlock().DefRange()
+			errs = append(errs, &hcl.Diagnostic{
+				Severity: hcl.DiagError,
+				Summary:  fmt.Sprintf("%s decode error", t.Address()),
+				Detail:   err.Error(),
+				Subject:  &subject,
+			})
+		}
+	}
+	return &analysis{
+		moduleRef: moduleRef,
+		cfg:       cfg,
+	}, errs
+}
+
+// unsetVariables assigns null to every variable without a default, so
+// planning never prompts for a value on the streams the server speaks on.
+func unsetVariables(hclBlocks []*golden.HclBlock) []golden.CliFlagAssignedVariables {
+	var r []golden.CliFlagAssignedVariables
+	for _, b := range hclBlocks {
+		if b.Type != "variable" || len(b.Labels) != 1 {
+			continue
+		}
+		if _, ok := b.Body.Attributes["default"]; ok {
+			continue
+		}
+		r = append(r, golden.NewCliFlagAssignedVariable(b.Labels[0], "null"))
+	}
+	return r
+}
+
+// publishSyntaxDiagnostics reports the syntax errors of the document at path,
+// or the diagnostics of the last full analysis when there are none.
+func (s *Server) publishSyntaxDiagnostics(path string) {
+	text := s.documents[path]
+	_, diags := hclsyntax.ParseConfig([]byte(text), path, hcl.InitialPos)
+	if !diags.HasErrors() {
+		s.publish(path, s.diagnostics[path])
+		return
+	}
+	var diagnostics []Diagnostic
+	for _, d := range diags {
+		if diagnostic, ok := diagnosticFor(path, text, d); ok {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	s.publish(path, diagnostics)
+}
+
+func flattenErrors(err error) []error {
+	var me *multierror.Error
+	if errors.As(err, &me) {
+		var r []error
+		for _, e := range me.Errors {
+			r = append(r, flattenErrors(e)...)
+		}
+		return r
+	}
+	var diags hcl.Diagnostics
+	if errors.As(err, &diags) {
+		var r []error
+		for _, d := range diags {
+			r = append(r, d)
+		}
+		return r
+	}
+	return []error{err}
+}
+
+// planDiagnostics checks the blocks of cfg one by one after planning it
+// failed with errs, since golden tells which block failed in text only. A
+// block failing to decode, to validate or a precondition gets a diagnostic on
+// its range, the blocks depending on it are left out. errs are kept as they
+// are when no block fails on its own, like a data block failing to execute.
+func planDiagnostics(cfg *pkg.MetaProgrammingTFConfig, errs []error) []error {
+	blocks := golden.Blocks[golden.Block](cfg)
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Address() < blocks[j].Address()
+	})
+	var r []error
+	failed := make(map[string]bool)
+	var fails func(b golden.Block) bool
+	fails = func(b golden.Block) bool {
+		address := b.Address()
+		if f, ok := failed[address]; ok {
+			return f
+		}
+		failed[address] = false
+		ancestors, err := cfg.GetAncestors(address)
+		if err != nil {
+			return false
+		}
+		for _, a := range ancestors {
+			if upstream, ok := a.(golden.Block); ok && fails(upstream) {
+				failed[address] = true
+				return true
+			}
+		}
+		diags := blockDiagnostics(b)
+		r = append(r, diags...)
+		failed[address] = len(diags) > 0
+		return failed[address]
+	}
+	for _, b := range blocks {
+		fails(b)
+	}
+	if len(r) == 0 {
+		return errs
+	}
+	return r
+}
+
+// blockDiagnostics decodes, validates and checks the preconditions of b the
+// way golden plans it.
+func blockDiagnostics(b golden.Block) []error {
+	subject := b.HclBlock().DefRange()
+	if err := golden.Decode(b); err != nil {
+		return []error{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("%s decode error", b.Address()),
+			Detail:   err.Error(),
+			Subject:  &subject,
+		}}
+	}
+	if err := golden.Validate.Struct(b); err != nil {
+		return []error{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  fmt.Sprintf("%s is not valid", b.Address()),
+			Detail:   err.Error(),
+			Subject:  &subject,
+		}}
+	}
+	failedChecks, err := b.PreConditionCheck(b.EvalContext())
+	if err != nil {
+		return flattenErrors(err)
+	}
+	var r []error
+	for _, c := range failedChecks {
+		rng := c.Body.Range()
+		r = append(r, &hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "precondition check error",
+			Detail:   c.ErrorMessage,
+			Subject:  &rng,
+		})
+	}
+	return r
+}
+
+// diagnosticFor places err in the document at path. Errors pointing at
+// another file belong to that file and are left out, errors without a range
+// are shown at the top of the document.
+func diagnosticFor(path, text string, err error) (Diagnostic, bool) {
+	d := Diagnostic{
+		Severity: SeverityError,
+		Source:   "mapotf",
+		Message:  err.Error(),
+	}
+	var hd *hcl.Diagnostic
+	if errors.As(err, &hd) {
+		if hd.Severity == hcl.DiagWarning {
+			d.Severity = SeverityWarning
+		}
+		d.Message = hd.Summary
+		if hd.Detail != "" {
+			d.Message = fmt.Sprintf("%s: %s", hd.Summary, hd.Detail)
+		}
+		if hd.Subject == nil {
+			return d, true
+		}
+		if !samePath(hd.Subject.Filename, path) {
+			return d, false
+		}
+		d.Range = Range{
+			Start: positionAt(text, hd.Subject.Start.Byte),
+			End:   positionAt(text, hd.Subject.End.Byte),
+		}
+		return d, true
+	}
+	return d, true
+}
+
+func samePath(a, b string) bool {
+	if filepath.Clean(a) == filepath.Clean(b) {
+		return true
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// declaredLabels returns the sorted labels of the `<kind> "<blockType>"`
+// blocks declared in the `.mptf.hcl` files of dir, or of every `kind` block
+// when blockType is empty.
+func (s *Server) declaredLabels(dir, kind, blockType string) []string {
+	seen := make(map[string]struct{})
+	for _, body := range s.mptfBodies(dir) {
+		for _, b := range body.Blocks {
+			if b.Type != kind {
+				continue
+			}
+			if blockType == "" && len(b.Labels) == 1 {
+				seen[b.Labels[0]] = struct{}{}
+			}
+			if blockType != "" && len(b.Labels) == 2 && b.Labels[0] == blockType {
+				seen[b.Labels[1]] = struct{}{}
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// declaredLocals returns the sorted names of the locals declared in dir.
+func (s *Server) declaredLocals(dir string) []string {
+	seen := make(map[string]struct{})
+	for _, body := range s.mptfBodies(dir) {
+		for _, b := range body.Blocks {
+			if b.Type != "locals" {
+				continue
+			}
+			for name := range b.Body.Attributes {
+				seen[name] = struct{}{}
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// mptfBodies parses every `.mptf.hcl` file of dir, preferring the open
+// documents over the files on disk. Parsing is best effort: the blocks
+// recovered from a file with syntax errors are kept.
+func (s *Server) mptfBodies(dir string) []*hclsyntax.Body {
+	contents := make(map[string][]byte)
+	if matches, err := afero.Glob(filesystem.Fs, filepath.Join(dir, "*.mptf.hcl")); err == nil {
+		for _, path := range matches {
+			if content, err := afero.ReadFile(filesystem.Fs, path); err == nil {
+				contents[path] = content
+			}
+		}
+	}
+	for _, path := range s.mptfDocuments(dir) {
+		contents[path] = []byte(s.documents[path])
+	}
+	var bodies []*hclsyntax.Body
+	for path, content := range contents {
+		file, _ := hclsyntax.ParseConfig(content, path, hcl.InitialPos)
+		if file == nil {
+			continue
+		}
+		if body, ok := file.Body.(*hclsyntax.Body); ok {
+			bodies = append(bodies, body)
+		}
+	}
+	return bodies
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	r := make([]string, 0, len(m))
+	for k := range m {
+		r = append(r, k)
+	}
+	sort.Strings(r)
+	return r
+}
@@ -0,0 +1,67 @@
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// blockHeaderTypeRegex matches a root block header, like
+// `transform "update_in_place" "tags" {`.
+var blockHeaderTypeRegex = regexp.MustCompile(`^\s*(transform|data)\s+"([\w-]+)"`)
+
+func (s *Server) hover(text string, pos Position) *Hover {
+	offset := offsetAt(text, pos)
+	start, end := wordAt(text, offset)
+	if start == end {
+		return nil
+	}
+	word := text[start:end]
+	r := &Range{Start: positionAt(text, start), End: positionAt(text, end)}
+	frames, inString := scope(text, start)
+	line := text[lineStart(text, start):lineEnd(text, start)]
+	if len(frames) == 0 {
+		m := blockHeaderTypeRegex.FindStringSubmatch(line)
+		if m == nil || m[2] != word {
+			return nil
+		}
+		if schema := schemas[m[1]][word]; schema != nil {
+			return &Hover{Contents: *markdown(schema.markdown()), Range: r}
+		}
+		return nil
+	}
+	if inString {
+		return nil
+	}
+	if strings.TrimSpace(text[lineStart(text, start):start]) == "" {
+		if schema := schemaAt(frames); schema != nil {
+			rest := strings.TrimSpace(text[end:lineEnd(text, end)])
+			if a, ok := schema.argument(word); ok && strings.HasPrefix(rest, "=") {
+				return &Hover{Contents: *markdown(fmt.Sprintf("%s\n\nArgument of %s", a.markdown(), schema.title())), Range: r}
+			}
+			if nested, ok := schema.Blocks[word]; ok && strings.HasPrefix(rest, "{") {
+				return &Hover{Contents: *markdown(nested.markdown()), Range: r}
+			}
+		}
+	}
+	// Only the steps of the reference up to the hovered one count, so hovering
+	// `resource` in `data.resource.all.result` documents the data block.
+	if i := strings.IndexByte(text[offset:end], '.'); i >= 0 {
+		end = offset + i
+		r.End = positionAt(text, end)
+	}
+	parts := strings.Split(text[start:end], ".")
+	if len(parts) < 2 || (parts[0] != "data" && parts[0] != "transform") {
+		return nil
+	}
+	schema := schemas[parts[0]][parts[1]]
+	if schema == nil {
+		return nil
+	}
+	if len(parts) >= 4 {
+		if a, ok := schema.attribute(parts[3]); ok {
+			return &Hover{Contents: *markdown(fmt.Sprintf("`%s` (%s)\n\nAttribute of %s", a.Name, a.Type, schema.title())), Range: r}
+		}
+	}
+	return &Hover{Contents: *markdown(schema.markdown()), Range: r}
+}
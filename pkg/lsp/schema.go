@@ -0,0 +1,236 @@
+package lsp
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Azure/mapotf/pkg"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// attributeSchema is an argument of a block, or an attribute a data block
+// exports.
+type attributeSchema struct {
+	Name     string
+	Type     string
+	Required bool
+}
+
+// blockSchema describes a `transform` or `data` block type, the `rule`
+// block, or a nested block of one.
+type blockSchema struct {
+	// Kind is `transform`, `data` or `rule` for root blocks, empty for nested
+	// blocks.
+	Kind      string
+	Name      string
+	Arguments []attributeSchema
+	Blocks    map[string]*blockSchema
+	// Attributes lists what `<kind>.<name>.<label>.` exposes: the arguments
+	// plus the computed attributes of data blocks.
+	Attributes []attributeSchema
+}
+
+var ctyValueType = reflect.TypeOf(cty.Value{})
+
+// Meta-arguments every block accepts, and the ones only transforms accept.
+var (
+	metaArguments = []attributeSchema{
+		{Name: "for_each", Type: "map or set"},
+		{Name: "depends_on", Type: "list"},
+	}
+	transformMetaArguments = []attributeSchema{
+		{Name: "condition", Type: "bool"},
+		{Name: "phase", Type: "string"},
+	}
+	preconditionBlock = &blockSchema{
+		Name: "precondition",
+		Arguments: []attributeSchema{
+			{Name: "condition", Type: "bool", Required: true},
+			{Name: "error_message", Type: "string", Required: true},
+		},
+	}
+)
+
+// bodyBlocks are the nested blocks decoded by hand rather than through `hcl`
+// tags, whose body is copied into the Terraform block.
+var bodyBlocks = map[string][]string{
+	"update_in_place": {"asraw", "asstring"},
+	"new_block":       {"asraw", "asstring"},
+}
+
+// schemas maps block kind, then block type, to its schema.
+var schemas = loadSchemas()
+
+func loadSchemas() map[string]map[string]*blockSchema {
+	r := make(map[string]map[string]*blockSchema)
+	for _, b := range pkg.RegisteredBlocks() {
+		kind := b.BlockType()
+		if r[kind] == nil {
+			r[kind] = make(map[string]*blockSchema)
+		}
+		s := structSchema(reflect.TypeOf(b))
+		s.Kind = kind
+		s.Name = b.Type()
+		s.Arguments = append(s.Arguments, metaArguments...)
+		if kind == "transform" {
+			s.Arguments = append(s.Arguments, transformMetaArguments...)
+		}
+		s.Blocks[preconditionBlock.Name] = preconditionBlock
+		for _, name := range bodyBlocks[s.Name] {
+			s.Blocks[name] = &blockSchema{Name: name}
+		}
+		r[kind][s.Name] = s
+	}
+	return r
+}
+
+func structSchema(t reflect.Type) *blockSchema {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	s := &blockSchema{
+		Blocks: make(map[string]*blockSchema),
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			continue
+		}
+		if tag, ok := f.Tag.Lookup("hcl"); ok {
+			parts := strings.Split(tag, ",")
+			if len(parts) > 1 && parts[1] == "block" {
+				nested := structSchema(f.Type)
+				nested.Name = parts[0]
+				s.Blocks[parts[0]] = nested
+				continue
+			}
+			a := attributeSchema{
+				Name:     parts[0],
+				Type:     typeName(f.Type),
+				Required: len(parts) == 1,
+			}
+			s.Arguments = append(s.Arguments, a)
+			s.Attributes = append(s.Attributes, a)
+			continue
+		}
+		if name, ok := f.Tag.Lookup("attribute"); ok {
+			s.Attributes = append(s.Attributes, attributeSchema{
+				Name: name,
+				Type: typeName(f.Type),
+			})
+		}
+	}
+	return s
+}
+
+func typeName(t reflect.Type) string {
+	if t == ctyValueType {
+		return "any"
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeName(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice:
+		return fmt.Sprintf("list(%s)", typeName(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map(%s)", typeName(t.Elem()))
+	}
+	return "object"
+}
+
+func (s *blockSchema) argument(name string) (attributeSchema, bool) {
+	for _, a := range s.Arguments {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return attributeSchema{}, false
+}
+
+func (s *blockSchema) attribute(name string) (attributeSchema, bool) {
+	for _, a := range s.Attributes {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return attributeSchema{}, false
+}
+
+func (s *blockSchema) title() string {
+	if s.Kind == "" {
+		return s.Name
+	}
+	if s.Name == "" {
+		return s.Kind
+	}
+	return fmt.Sprintf("%s %q", s.Kind, s.Name)
+}
+
+// markdown documents the block for hovers and completion items.
+func (s *blockSchema) markdown() string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "**%s**\n", s.title())
+	if len(s.Arguments) > 0 {
+		sb.WriteString("\nArguments:\n")
+		for _, a := range s.Arguments {
+			fmt.Fprintf(sb, "- %s\n", a.markdown())
+		}
+	}
+	if names := s.blockNames(); len(names) > 0 {
+		sb.WriteString("\nBlocks:\n")
+		for _, n := range names {
+			fmt.Fprintf(sb, "- `%s`\n", n)
+		}
+	}
+	var computed []attributeSchema
+	for _, a := range s.Attributes {
+		if _, ok := s.argument(a.Name); !ok {
+			computed = append(computed, a)
+		}
+	}
+	if len(computed) > 0 {
+		sb.WriteString("\nAttributes:\n")
+		for _, a := range computed {
+			fmt.Fprintf(sb, "- `%s` (%s)\n", a.Name, a.Type)
+		}
+	}
+	return sb.String()
+}
+
+func (s *blockSchema) blockNames() []string {
+	var names []string
+	for n := range s.Blocks {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a attributeSchema) detail() string {
+	if a.Required {
+		return a.Type + ", required"
+	}
+	return a.Type + ", optional"
+}
+
+func (a attributeSchema) markdown() string {
+	return fmt.Sprintf("`%s` (%s)", a.Name, a.detail())
+}
+
+// blockTypes returns the sorted block types of kind.
+func blockTypes(kind string) []string {
+	var r []string
+	for name := range schemas[kind] {
+		r = append(r, name)
+	}
+	sort.Strings(r)
+	return r
+}
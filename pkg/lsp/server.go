@@ -0,0 +1,259 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Azure/mapotf/pkg"
+)
+
+// Server is a language server for `.mptf.hcl` files, speaking LSP over a
+// pair of streams, usually stdin and stdout.
+//
+// Completion and hover come from the schemas of the registered `transform`,
+// `data` and `rule` blocks. Syntax errors are reported on every change, while the
+// whole mptf dir is loaded, planned and decoded against the Terraform module
+// in tfDir when a document is opened or saved, since data blocks may be slow.
+type Server struct {
+	tfDir     string
+	tfOptions pkg.TerraformOptions
+	in        *bufio.Reader
+	out       io.Writer
+	// outMu serializes writes to out.
+	outMu sync.Mutex
+	// documents holds the content of every open document, keyed by path.
+	documents map[string]string
+	// diagnostics holds the last diagnostics of the full analysis, keyed by
+	// path, so a change without syntax errors doesn't clear them.
+	diagnostics map[string][]Diagnostic
+	// analyses holds the last successful analysis of each mptf dir.
+	analyses map[string]*analysis
+	shutdown bool
+}
+
+// NewServer returns a server analysing mptf dirs against the Terraform module
+// in tfDir, loaded as tfOptions tells.
+func NewServer(tfDir string, tfOptions pkg.TerraformOptions, in io.Reader, out io.Writer) *Server {
+	return &Server{
+		tfDir:       tfDir,
+		tfOptions:   tfOptions,
+		in:          bufio.NewReader(in),
+		out:         out,
+		documents:   make(map[string]string),
+		diagnostics: make(map[string][]Diagnostic),
+		analyses:    make(map[string]*analysis),
+	}
+}
+
+// Serve handles messages until the client sends `exit` or closes in.
+func (s *Server) Serve(ctx context.Context) error {
+	for {
+		body, err := readMessage(s.in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err = json.Unmarshal(body, &req); err != nil {
+			s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit received before shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(ctx, req)
+		if req.isNotification() {
+			continue
+		}
+		if err != nil {
+			var re *responseError
+			if !errors.As(err, &re) {
+				re = &responseError{Code: codeInternalError, Message: err.Error()}
+			}
+			s.reply(req.ID, nil, re)
+			continue
+		}
+		s.reply(req.ID, result, nil)
+	}
+}
+
+func (s *Server) handle(ctx context.Context, req request) (any, error) {
+	switch req.Method {
+	case "initialize":
+		return InitializeResult{
+			Capabilities: ServerCapabilities{
+				TextDocumentSync: TextDocumentSyncOptions{
+					OpenClose: true,
+					Change:    textDocumentSyncFull,
+					Save:      true,
+				},
+				CompletionProvider: CompletionOptions{
+					TriggerCharacters: []string{".", `"`},
+				},
+				HoverProvider:      true,
+				DefinitionProvider: true,
+			},
+			ServerInfo: ServerInfo{Name: "mapotf"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		path := uriToPath(p.TextDocument.URI)
+		s.documents[path] = p.TextDocument.Text
+		s.analyzeDir(ctx, filepath.Dir(path))
+		return nil, nil
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		if len(p.ContentChanges) == 0 {
+			return nil, nil
+		}
+		path := uriToPath(p.TextDocument.URI)
+		s.documents[path] = p.ContentChanges[len(p.ContentChanges)-1].Text
+		s.publishSyntaxDiagnostics(path)
+		return nil, nil
+	case "textDocument/didSave":
+		var p DidSaveTextDocumentParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		s.analyzeDir(ctx, filepath.Dir(uriToPath(p.TextDocument.URI)))
+		return nil, nil
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		path := uriToPath(p.TextDocument.URI)
+		delete(s.documents, path)
+		delete(s.diagnostics, path)
+		s.publish(path, nil)
+		return nil, nil
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		path := uriToPath(p.TextDocument.URI)
+		return s.completion(path, s.documents[path], p.Position), nil
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		path := uriToPath(p.TextDocument.URI)
+		return s.hover(s.documents[path], p.Position), nil
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		if err := decodeParams(req, &p); err != nil {
+			return nil, err
+		}
+		path := uriToPath(p.TextDocument.URI)
+		return s.definition(ctx, path, s.documents[path], p.Position), nil
+	}
+	if req.isNotification() {
+		return nil, nil
+	}
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q is not supported", req.Method)}
+}
+
+func decodeParams(req request, v any) error {
+	if err := json.Unmarshal(req.Params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("invalid params of %s: %+v", req.Method, err)}
+	}
+	return nil
+}
+
+func (s *Server) reply(id json.RawMessage, result any, err *responseError) {
+	msg := map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+	}
+	if id == nil {
+		msg["id"] = nil
+	}
+	if err != nil {
+		msg["error"] = err
+	} else {
+		msg["result"] = result
+	}
+	s.send(msg)
+}
+
+func (s *Server) notify(method string, params any) {
+	s.send(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	})
+}
+
+func (s *Server) send(msg any) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	_ = writeMessage(s.out, msg)
+}
+
+func (s *Server) publish(path string, diagnostics []Diagnostic) {
+	if diagnostics == nil {
+		diagnostics = []Diagnostic{}
+	}
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         pathToURI(path),
+		Diagnostics: diagnostics,
+	})
+}
+
+// mptfDocuments returns the open `.mptf.hcl` documents of dir.
+func (s *Server) mptfDocuments(dir string) []string {
+	var r []string
+	for path := range s.documents {
+		if filepath.Dir(path) == dir && strings.HasSuffix(path, ".mptf.hcl") {
+			r = append(r, path)
+		}
+	}
+	return r
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	path := u.Path
+	// `file:///c:/dir` on Windows.
+	if len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.Clean(filepath.FromSlash(path))
+}
+
+func pathToURI(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
@@ -0,0 +1,536 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/Azure/mapotf/pkg/lsp"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mptfPath = "/mptf/main.mptf.hcl"
+	mptfURI  = "file:///mptf/main.mptf.hcl"
+)
+
+const terraformCode = `resource "fake_resource" "this" {
+  tags = {}
+}
+
+resource "fake_resource" "that" {
+}
+
+data "fake_data" "this" {
+}
+`
+
+const mptfCode = `data "resource" all {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" tags {
+  target_block_address = "resource.fake_resource.this"
+  asstring {
+    tags = "{}"
+  }
+}
+`
+
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type session struct {
+	in     bytes.Buffer
+	nextID int
+}
+
+func (s *session) request(method string, params any) int {
+	s.nextID++
+	s.write(map[string]any{"jsonrpc": "2.0", "id": s.nextID, "method": method, "params": params})
+	return s.nextID
+}
+
+func (s *session) notify(method string, params any) {
+	s.write(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (s *session) write(msg any) {
+	body, _ := json.Marshal(msg)
+	_, _ = fmt.Fprintf(&s.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *session) open(text string) {
+	s.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": mptfURI, "languageId": "hcl", "version": 1, "text": text},
+	})
+}
+
+func (s *session) at(method string, text, marker string) int {
+	offset := strings.Index(text, marker) + len(marker)
+	line := strings.Count(text[:offset], "\n")
+	character := offset - strings.LastIndex(text[:offset], "\n") - 1
+	return s.request(method, map[string]any{
+		"textDocument": map[string]any{"uri": mptfURI},
+		"position":     map[string]any{"line": line, "character": character},
+	})
+}
+
+// serve runs the server over the messages of the session, ended by
+// `shutdown` and `exit`, and returns what it wrote.
+func (s *session) serve(t *testing.T) []message {
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+	out := &bytes.Buffer{}
+	require.NoError(t, lsp.NewServer("/tf", pkg.TerraformOptions{}, &s.in, out).Serve(context.Background()))
+	var messages []message
+	r := bufio.NewReader(out)
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err == io.EOF {
+			return messages
+		}
+		require.NoError(t, err)
+		length, err := strconv.Atoi(header.Get("Content-Length"))
+		require.NoError(t, err)
+		body := make([]byte, length)
+		_, err = io.ReadFull(r, body)
+		require.NoError(t, err)
+		var m message
+		require.NoError(t, json.Unmarshal(body, &m))
+		messages = append(messages, m)
+	}
+}
+
+func response(t *testing.T, messages []message, id int, v any) {
+	for _, m := range messages {
+		if m.ID != nil && *m.ID == id && m.Method == "" {
+			require.Nil(t, m.Error)
+			require.NoError(t, json.Unmarshal(m.Result, v))
+			return
+		}
+	}
+	t.Fatalf("no response to request %d", id)
+}
+
+func diagnostics(t *testing.T, messages []message) [][]lsp.Diagnostic {
+	var r [][]lsp.Diagnostic
+	for _, m := range messages {
+		if m.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p lsp.PublishDiagnosticsParams
+		require.NoError(t, json.Unmarshal(m.Params, &p))
+		assert.Equal(t, mptfURI, p.URI)
+		r = append(r, p.Diagnostics)
+	}
+	return r
+}
+
+func labels(items []lsp.CompletionItem) []string {
+	var r []string
+	for _, i := range items {
+		r = append(r, i.Label)
+	}
+	return r
+}
+
+func stubFs(t *testing.T, mptf string) {
+	fs := afero.NewMemMapFs()
+	_ = afero.WriteFile(fs, "/tf/main.tf", []byte(terraformCode), 0644)
+	_ = afero.WriteFile(fs, mptfPath, []byte(mptf), 0644)
+	stub := gostub.Stub(&filesystem.Fs, fs)
+	t.Cleanup(stub.Reset)
+}
+
+func TestServer_Initialize(t *testing.T) {
+	stubFs(t, mptfCode)
+	s := &session{}
+	id := s.request("initialize", map[string]any{"processId": nil, "rootUri": "file:///mptf"})
+	s.notify("initialized", map[string]any{})
+	messages := s.serve(t)
+
+	var result lsp.InitializeResult
+	response(t, messages, id, &result)
+	assert.True(t, result.Capabilities.HoverProvider)
+	assert.True(t, result.Capabilities.DefinitionProvider)
+	assert.Contains(t, result.Capabilities.CompletionProvider.TriggerCharacters, ".")
+}
+
+func TestServer_UnknownMethodIsAnError(t *testing.T) {
+	stubFs(t, mptfCode)
+	s := &session{}
+	id := s.request("workspace/symbol", map[string]any{"query": ""})
+	messages := s.serve(t)
+
+	for _, m := range messages {
+		if m.ID != nil && *m.ID == id {
+			require.NotNil(t, m.Error)
+			assert.Equal(t, -32601, m.Error.Code)
+			return
+		}
+	}
+	t.Fatal("no response to the unknown method")
+}
+
+func TestServer_ExitBeforeShutdownIsAnError(t *testing.T) {
+	s := &session{}
+	s.notify("exit", nil)
+	err := lsp.NewServer("/tf", pkg.TerraformOptions{}, &s.in, io.Discard).Serve(context.Background())
+	assert.Error(t, err)
+}
+
+func TestServer_ValidDocumentHasNoDiagnostics(t *testing.T) {
+	stubFs(t, mptfCode)
+	s := &session{}
+	s.open(mptfCode)
+	published := diagnostics(t, s.serve(t))
+
+	require.Len(t, published, 1)
+	assert.Empty(t, published[0])
+}
+
+func TestServer_SyntaxErrorIsReportedOnChange(t *testing.T) {
+	stubFs(t, mptfCode)
+	s := &session{}
+	s.open(mptfCode)
+	s.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": mptfURI, "version": 2},
+		"contentChanges": []any{map[string]any{"text": "transform \"update_in_place\" tags {\n  target_block_address = \n}\n"}},
+	})
+	published := diagnostics(t, s.serve(t))
+
+	require.Len(t, published, 2)
+	require.NotEmpty(t, published[1])
+	assert.Equal(t, lsp.SeverityError, published[1][0].Severity)
+	assert.Equal(t, 1, published[1][0].Range.Start.Line)
+}
+
+func TestServer_UnknownTargetBlockIsReportedOnOpen(t *testing.T) {
+	code := strings.Replace(mptfCode, "resource.fake_resource.this", "resource.fake_resource.missing", 1)
+	stubFs(t, code)
+	s := &session{}
+	s.open(code)
+	published := diagnostics(t, s.serve(t))
+
+	require.Len(t, published, 1)
+	require.Len(t, published[0], 1)
+	assert.Contains(t, published[0][0].Message, "resource.fake_resource.missing")
+	assert.Equal(t, 4, published[0][0].Range.Start.Line)
+}
+
+func TestServer_PlanErrorIsReportedOnItsBlock(t *testing.T) {
+	code := `data "resource" all {
+  resource_type = "fake_resource"
+  precondition {
+    condition     = false
+    error_message = "always fails"
+  }
+}
+`
+	stubFs(t, code)
+	s := &session{}
+	s.open(code)
+	published := diagnostics(t, s.serve(t))
+
+	require.Len(t, published, 1)
+	require.Len(t, published[0], 1)
+	assert.Contains(t, published[0][0].Message, "always fails")
+	assert.Equal(t, 2, published[0][0].Range.Start.Line)
+}
+
+func TestServer_BlocksDependingOnAFailedBlockAreNotReported(t *testing.T) {
+	code := `data "resource" all {
+  resource_type = "fake_resource"
+  precondition {
+    condition     = false
+    error_message = "always fails"
+  }
+}
+
+transform "update_in_place" tags {
+  for_each             = data.resource.all.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  asstring {
+    tags = "{}"
+  }
+}
+`
+	stubFs(t, code)
+	s := &session{}
+	s.open(code)
+	published := diagnostics(t, s.serve(t))
+
+	require.Len(t, published, 1)
+	require.Len(t, published[0], 1)
+	assert.Contains(t, published[0][0].Message, "always fails")
+}
+
+func TestServer_ErrorWithoutRangeIsReportedAtFileLevel(t *testing.T) {
+	code := mptfCode + `
+transform "update_in_place" later {
+  phase                = "later"
+  target_block_address = "resource.fake_resource.this"
+  asstring {
+    tags = "{}"
+  }
+}
+
+transform "update_in_place" first {
+  depends_on           = [transform.update_in_place.later]
+  target_block_address = "resource.fake_resource.this"
+  asstring {
+    tags = "{}"
+  }
+}
+`
+	stubFs(t, code)
+	s := &session{}
+	s.open(code)
+	published := diagnostics(t, s.serve(t))
+
+	require.Len(t, published, 1)
+	require.Len(t, published[0], 1)
+	assert.Contains(t, published[0][0].Message, "in the later phase")
+	assert.Equal(t, lsp.Range{}, published[0][0].Range)
+}
+
+func TestServer_UnsavedContentIsAnalyzed(t *testing.T) {
+	stubFs(t, mptfCode)
+	code := strings.Replace(mptfCode, "resource.fake_resource.this", "resource.fake_resource.missing", 1)
+	s := &session{}
+	s.open(code)
+	published := diagnostics(t, s.serve(t))
+
+	require.Len(t, published, 1)
+	assert.Len(t, published[0], 1)
+	content, err := afero.ReadFile(filesystem.Fs, mptfPath)
+	require.NoError(t, err)
+	assert.Equal(t, mptfCode, string(content))
+}
+
+func TestServer_Completion(t *testing.T) {
+	cases := []struct {
+		desc     string
+		text     string
+		marker   string
+		contains []string
+		excludes []string
+	}{
+		{
+			desc:     "root keywords",
+			text:     "tr",
+			marker:   "tr",
+			contains: []string{"data", "locals", "rule", "transform", "variable"},
+		},
+		{
+			desc:     "transform types",
+			text:     `transform "upd`,
+			marker:   `transform "upd`,
+			contains: []string{"update_in_place", "new_block", "remove_block_element"},
+			excludes: []string{"resource"},
+		},
+		{
+			desc:     "data types",
+			text:     `data "`,
+			marker:   `data "`,
+			contains: []string{"resource", "terraform"},
+			excludes: []string{"update_in_place"},
+		},
+		{
+			desc:     "transform arguments and blocks",
+			text:     "transform \"update_in_place\" tags {\n  \n}\n",
+			marker:   "{\n  ",
+			contains: []string{"target_block_address", "for_each", "depends_on", "phase", "asstring", "asraw"},
+		},
+		{
+			desc:     "rule arguments",
+			text:     "rule \"untagged\" {\n  \n}\n",
+			marker:   "{\n  ",
+			contains: []string{"severity", "message", "targets", "for_each"},
+			excludes: []string{"phase"},
+		},
+		{
+			desc:     "data block attributes",
+			text:     mptfCode + "locals {\n  x = data.resource.all.\n}\n",
+			marker:   "data.resource.all.",
+			contains: []string{"resource_type", "result"},
+		},
+		{
+			desc:     "data block labels",
+			text:     mptfCode + "locals {\n  x = data.resource.\n}\n",
+			marker:   "data.resource.",
+			contains: []string{"all"},
+		},
+		{
+			desc:     "resource types of a result",
+			text:     mptfCode + "locals {\n  x = data.resource.all.result.\n}\n",
+			marker:   "data.resource.all.result.",
+			contains: []string{"fake_resource"},
+		},
+		{
+			desc:     "resource names of a result",
+			text:     mptfCode + "locals {\n  x = data.resource.all.result.fake_resource.\n}\n",
+			marker:   "data.resource.all.result.fake_resource.",
+			contains: []string{"this", "that"},
+		},
+		{
+			desc:     "attributes of a resource",
+			text:     mptfCode + "locals {\n  x = data.resource.all.result.fake_resource.this.\n}\n",
+			marker:   "data.resource.all.result.fake_resource.this.",
+			contains: []string{"mptf", "tags"},
+		},
+		{
+			desc:     "data source types of a result",
+			text:     "data \"data\" all {\n}\n\nlocals {\n  x = data.data.all.result.\n}\n",
+			marker:   "data.data.all.result.",
+			contains: []string{"fake_data"},
+		},
+		{
+			desc:     "mptf keys",
+			text:     mptfCode + "locals {\n  x = data.resource.all.result.fake_resource.this.mptf.\n}\n",
+			marker:   ".mptf.",
+			contains: []string{"block_address", "terraform_address", "module", "range"},
+		},
+		{
+			desc:     "nothing inside strings",
+			text:     "transform \"update_in_place\" tags {\n  target_block_address = \"resource.\"\n}\n",
+			marker:   `"resource.`,
+			excludes: []string{"fake_resource"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stubFs(t, c.text)
+			s := &session{}
+			s.open(c.text)
+			id := s.at("textDocument/completion", c.text, c.marker)
+			var items []lsp.CompletionItem
+			response(t, s.serve(t), id, &items)
+			got := labels(items)
+			for _, l := range c.contains {
+				assert.Contains(t, got, l)
+			}
+			for _, l := range c.excludes {
+				assert.NotContains(t, got, l)
+			}
+		})
+	}
+}
+
+func TestServer_Hover(t *testing.T) {
+	cases := []struct {
+		desc     string
+		marker   string
+		contains string
+	}{
+		{
+			desc:     "block type",
+			marker:   `transform "update_in`,
+			contains: "target_block_address",
+		},
+		{
+			desc:     "argument",
+			marker:   "  target_block",
+			contains: "`target_block_address` (string, required)",
+		},
+		{
+			desc:     "data block type in a reference",
+			marker:   mptfCode + "locals {\n  x = data.reso",
+			contains: "resource_type",
+		},
+		{
+			desc:     "data block attribute in a reference",
+			marker:   "all.res",
+			contains: "Attribute of data \"resource\"",
+		},
+	}
+	text := mptfCode + "locals {\n  x = data.resource.all.result\n}\n"
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stubFs(t, text)
+			s := &session{}
+			s.open(text)
+			id := s.at("textDocument/hover", text, c.marker)
+			var hover lsp.Hover
+			response(t, s.serve(t), id, &hover)
+			assert.Contains(t, hover.Contents.Value, c.contains)
+		})
+	}
+}
+
+func TestServer_HoverOnNothingIsNull(t *testing.T) {
+	stubFs(t, mptfCode)
+	s := &session{}
+	s.open(mptfCode)
+	id := s.at("textDocument/hover", mptfCode, "resource_type = ")
+	var hover *lsp.Hover
+	response(t, s.serve(t), id, &hover)
+	assert.Nil(t, hover)
+}
+
+func TestServer_DefinitionOfTargetBlockAddress(t *testing.T) {
+	cases := []struct {
+		desc   string
+		text   string
+		wanted []int
+	}{
+		{
+			desc:   "string literal",
+			text:   mptfCode,
+			wanted: []int{0},
+		},
+		{
+			desc: "for_each expression",
+			text: `data "resource" all {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" tags {
+  for_each = toset(["this", "that"])
+  target_block_address = "resource.fake_resource.${each.value}"
+  asstring {
+    tags = "{}"
+  }
+}
+`,
+			wanted: []int{0, 4},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stubFs(t, c.text)
+			s := &session{}
+			s.open(c.text)
+			id := s.at("textDocument/definition", c.text, "target_block")
+			var locations []lsp.Location
+			response(t, s.serve(t), id, &locations)
+			var lines []int
+			for _, l := range locations {
+				assert.Equal(t, "file:///tf/main.tf", l.URI)
+				lines = append(lines, l.Range.Start.Line)
+			}
+			assert.ElementsMatch(t, c.wanted, lines)
+		})
+	}
+}
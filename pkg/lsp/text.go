@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// frame is a `{ ... }` enclosing a position.
+type frame struct {
+	// block is false for object expressions and template interpolations.
+	block bool
+	// words holds the unquoted block type and labels of a block.
+	words []string
+	// template marks a `${ ... }` interpolation inside a string.
+	template bool
+}
+
+var (
+	blockHeaderRegex = regexp.MustCompile(`^\s*[A-Za-z_][\w-]*(?:\s*(?:"[^"\n]*"|[A-Za-z_][\w-]*))*\s*$`)
+	headerWordRegex  = regexp.MustCompile(`"[^"\n]*"|[A-Za-z_][\w-]*`)
+	heredocRegex     = regexp.MustCompile(`^<<-?([A-Za-z_][\w-]*)[ \t]*\r?\n`)
+)
+
+// scope returns the frames enclosing offset, outermost first, and whether
+// offset is inside a string literal. It scans the text by hand instead of
+// parsing it so that it keeps working on the incomplete documents completion
+// is asked for.
+func scope(text string, offset int) ([]frame, bool) {
+	var stack []frame
+	inString := false
+	headerStart := 0
+	heredoc := ""
+	for i := 0; i < offset && i < len(text); i++ {
+		c := text[i]
+		if heredoc != "" {
+			if c == '\n' {
+				line := text[i+1:]
+				if end := strings.IndexByte(line, '\n'); end >= 0 {
+					line = line[:end]
+				}
+				if strings.TrimSpace(line) == heredoc {
+					heredoc = ""
+					i += len(line)
+					headerStart = i + 1
+				}
+			}
+			continue
+		}
+		if inString {
+			switch {
+			case c == '\\':
+				i++
+			case c == '"':
+				inString = false
+			case c == '$' && i+1 < len(text) && text[i+1] == '{':
+				stack = append(stack, frame{template: true})
+				inString = false
+				i++
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '#':
+			i = skipTo(text, i, "\n") - 1
+		case '/':
+			if strings.HasPrefix(text[i:], "//") {
+				i = skipTo(text, i, "\n") - 1
+			} else if strings.HasPrefix(text[i:], "/*") {
+				i = skipTo(text, i, "*/") + 1
+			}
+		case '<':
+			if m := heredocRegex.FindStringSubmatch(text[i:]); m != nil {
+				heredoc = m[1]
+				i += len(m[0]) - 2
+			}
+		case '{':
+			header := text[headerStart:i]
+			f := frame{}
+			if blockHeaderRegex.MatchString(header) {
+				f.block = true
+				for _, w := range headerWordRegex.FindAllString(header, -1) {
+					f.words = append(f.words, strings.Trim(w, `"`))
+				}
+			}
+			stack = append(stack, f)
+			headerStart = i + 1
+		case '}':
+			if len(stack) > 0 {
+				popped := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				inString = popped.template
+			}
+			headerStart = i + 1
+		case '\n':
+			headerStart = i + 1
+		}
+	}
+	return stack, inString
+}
+
+// skipTo returns the offset of the next marker after i, or the end of text.
+func skipTo(text string, i int, marker string) int {
+	if end := strings.Index(text[i+1:], marker); end >= 0 {
+		return i + 1 + end
+	}
+	return len(text)
+}
+
+// rootBlock returns the words of the root block enclosing frames, and the
+// nested block types between it and the innermost frame. ok is false when an
+// expression or interpolation is in between.
+func rootBlock(frames []frame) (root []string, nested []string, ok bool) {
+	if len(frames) == 0 {
+		return nil, nil, false
+	}
+	for _, f := range frames {
+		if !f.block || len(f.words) == 0 {
+			return nil, nil, false
+		}
+	}
+	for _, f := range frames[1:] {
+		nested = append(nested, f.words[0])
+	}
+	return frames[0].words, nested, true
+}
+
+// schemaAt returns the schema of the innermost block of frames.
+func schemaAt(frames []frame) *blockSchema {
+	root, nested, ok := rootBlock(frames)
+	if !ok || len(root) < 2 {
+		return nil
+	}
+	s := schemas[root[0]][root[1]]
+	if s == nil {
+		// Blocks such as `rule` have a name but no type.
+		s = schemas[root[0]][""]
+	}
+	for _, n := range nested {
+		if s == nil {
+			return nil
+		}
+		s = s.Blocks[n]
+	}
+	return s
+}
+
+func lineStart(text string, offset int) int {
+	return strings.LastIndexByte(text[:offset], '\n') + 1
+}
+
+func lineEnd(text string, offset int) int {
+	if end := strings.IndexByte(text[offset:], '\n'); end >= 0 {
+		return offset + end
+	}
+	return len(text)
+}
+
+// wordAt returns the start and end offsets of the dotted identifier around
+// offset, like `data.resource.all`.
+func wordAt(text string, offset int) (int, int) {
+	isWordChar := func(c byte) bool {
+		return c == '_' || c == '-' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+	start, end := offset, offset
+	for start > 0 && isWordChar(text[start-1]) {
+		start--
+	}
+	for end < len(text) && isWordChar(text[end]) {
+		end++
+	}
+	return start, end
+}
+
+// offsetAt converts an LSP position, whose character counts UTF-16 code
+// units, to a byte offset in text.
+func offsetAt(text string, p Position) int {
+	offset := 0
+	for line := 0; line < p.Line; line++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+	for units := 0; units < p.Character && offset < len(text) && text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+// positionAt converts a byte offset in text to an LSP position.
+func positionAt(text string, offset int) Position {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	start := lineStart(text, offset)
+	return Position{
+		Line:      strings.Count(text[:start], "\n"),
+		Character: len(utf16.Encode([]rune(text[start:offset]))),
+	}
+}
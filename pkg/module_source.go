@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -35,11 +34,14 @@ type TerraformModuleSourceFetcher interface {
 }
 
 type TerraformCliModuleSourceFetcher struct {
-	ctx context.Context
+	ctx             context.Context
+	terraformBinary string
 }
 
-func NewTerraformCliModuleSourceFetcher(ctx context.Context) TerraformModuleSourceFetcher {
-	return TerraformCliModuleSourceFetcher{ctx: ctx}
+// NewTerraformCliModuleSourceFetcher returns a fetcher running the
+// terraformBinary CLI, see TerraformOptions.
+func NewTerraformCliModuleSourceFetcher(ctx context.Context, terraformBinary string) TerraformModuleSourceFetcher {
+	return TerraformCliModuleSourceFetcher{ctx: ctx, terraformBinary: terraformBinary}
 }
 
 func (t TerraformCliModuleSourceFetcher) Get(source, version, baseDir string) (*tfconfig.Module, error) {
@@ -169,17 +171,7 @@ func hasTerraformFiles(dir string) bool {
 }
 
 func (t TerraformCliModuleSourceFetcher) getTerraformPath() (string, error) {
-	var cmd *exec.Cmd
-	if t.isWindows() {
-		cmd = exec.Command("where", "terraform")
-	} else {
-		cmd = exec.Command("which", "terraform")
-	}
-	out, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimSpace(string(out)), nil
+	return findTerraformBinary(t.terraformBinary, t.isWindows())
 }
 
 func (t TerraformCliModuleSourceFetcher) isWindows() bool {
@@ -73,7 +73,7 @@ output "id" {
 }
 `), 0o600))
 
-	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background())
+	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background(), "terraform")
 	mod, err := sut.Get("./submod", "", baseDir)
 	require.NoError(t, err)
 	require.NotNil(t, mod)
@@ -88,7 +88,7 @@ output "id" {
 
 func TestTerraformCliModuleSourceFetcher_LocalSourceMissingBaseDir(t *testing.T) {
 	t.Parallel()
-	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background())
+	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background(), "terraform")
 	_, err := sut.Get("./submod", "", "")
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "base_dir", "error must explain that base_dir is required for local sources")
@@ -97,7 +97,7 @@ func TestTerraformCliModuleSourceFetcher_LocalSourceMissingBaseDir(t *testing.T)
 func TestTerraformCliModuleSourceFetcher_LocalSourceMissingDirectory(t *testing.T) {
 	t.Parallel()
 	baseDir := t.TempDir()
-	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background())
+	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background(), "terraform")
 	_, err := sut.Get("./does-not-exist", "", baseDir)
 	require.Error(t, err)
 }
@@ -116,7 +116,7 @@ func TestTerraformCliModuleSourceFetcher_RemoteSourceToleratesValidationError(t
 	// has a default), so it's the safest registry module to exercise the
 	// remote-fetch happy path without paying the cost of a flaky network
 	// dependency on a module with required args.
-	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background())
+	sut := pkg.NewTerraformCliModuleSourceFetcher(context.Background(), "terraform")
 	mod, err := sut.Get("Azure/naming/azurerm", "0.4.0", "")
 	require.NoError(t, err)
 	require.NotNil(t, mod)
@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/spf13/afero"
+	"github.com/zclconf/go-cty/cty"
 	"github.com/zclconf/go-cty/cty/function"
 )
 
@@ -30,12 +31,23 @@ type MetaProgrammingTFConfig struct {
 	outputBlocks    map[string]*terraform.RootBlock
 	moduleBlocks    map[string]*terraform.RootBlock
 	movedBlocks     map[string]*terraform.RootBlock
+	providerBlocks  map[string]*terraform.RootBlock
+	importBlocks    map[string]*terraform.RootBlock
+	removedBlocks   map[string]*terraform.RootBlock
+	checkBlocks     map[string]*terraform.RootBlock
 	terraformBlock  *terraform.RootBlock
 	allRootBlocks   []*terraform.RootBlock
 	module          *terraform.Module
+	transformOrder  *transformOrder
+	// transformMeta holds the meta-arguments of the transform blocks by
+	// address, see stripTransformMetaAttributes.
+	transformMeta    map[string]hclsyntax.Attributes
+	terraformOptions TerraformOptions
 }
 
-func NewMetaProgrammingTFConfig(m *TerraformModuleRef, varConfigDir *string, hclBlocks []*golden.HclBlock, cliFlagAssignedVars []golden.CliFlagAssignedVariables, ctx context.Context) (*MetaProgrammingTFConfig, error) {
+// NewMetaProgrammingTFConfig loads the Terraform module of m as opts tells and
+// initializes hclBlocks against it.
+func NewMetaProgrammingTFConfig(m *TerraformModuleRef, varConfigDir *string, hclBlocks []*golden.HclBlock, cliFlagAssignedVars []golden.CliFlagAssignedVariables, opts TerraformOptions, ctx context.Context) (*MetaProgrammingTFConfig, error) {
 	baseConfig := golden.NewBasicConfigFromArgs(golden.NewBaseConfigArgs{
 		Basedir:                  m.AbsDir,
 		DslFullName:              "mapotf",
@@ -49,16 +61,23 @@ func NewMetaProgrammingTFConfig(m *TerraformModuleRef, varConfigDir *string, hcl
 		"tohcl": ToHclFunc,
 	}
 	cfg := &MetaProgrammingTFConfig{
-		BaseConfig: baseConfig,
+		BaseConfig:       baseConfig,
+		terraformOptions: opts,
 	}
 	if err := cfg.reloadTerraformModule(m); err != nil {
 		return nil, err
 	}
+	order, err := newTransformOrder(hclBlocks)
+	if err != nil {
+		return nil, err
+	}
+	cfg.transformOrder = order
+	cfg.transformMeta = stripTransformMetaAttributes(hclBlocks)
 	return cfg, golden.InitConfig(cfg, hclBlocks)
 }
 
 func (c *MetaProgrammingTFConfig) reloadTerraformModule(m *TerraformModuleRef) error {
-	module, err := terraform.LoadModule(m.toTerraformPkgType())
+	module, err := terraform.LoadModule(m.toTerraformPkgType(), c.terraformOptions.LoadOptions)
 	if err != nil {
 		return err
 	}
@@ -70,6 +89,10 @@ func (c *MetaProgrammingTFConfig) reloadTerraformModule(m *TerraformModuleRef) e
 	c.outputBlocks = groupByAddress(module.Outputs)
 	c.localBlocks = groupByAddress(module.Locals)
 	c.movedBlocks = groupByAddress(module.MovedBlocks)
+	c.providerBlocks = groupByAddress(module.ProviderBlocks)
+	c.importBlocks = groupByAddress(module.ImportBlocks)
+	c.removedBlocks = groupByAddress(module.RemovedBlocks)
+	c.checkBlocks = groupByAddress(module.CheckBlocks)
 	if len(module.TerraformBlocks) > 0 {
 		c.terraformBlock = module.TerraformBlocks[0]
 	}
@@ -114,10 +137,31 @@ func (c *MetaProgrammingTFConfig) MovedBlocks() []*terraform.RootBlock {
 	return c.slice(c.movedBlocks)
 }
 
+func (c *MetaProgrammingTFConfig) ProviderBlocks() []*terraform.RootBlock {
+	return c.slice(c.providerBlocks)
+}
+
+func (c *MetaProgrammingTFConfig) ImportBlocks() []*terraform.RootBlock {
+	return c.slice(c.importBlocks)
+}
+
+func (c *MetaProgrammingTFConfig) RemovedBlocks() []*terraform.RootBlock {
+	return c.slice(c.removedBlocks)
+}
+
+func (c *MetaProgrammingTFConfig) CheckBlocks() []*terraform.RootBlock {
+	return c.slice(c.checkBlocks)
+}
+
 func (c *MetaProgrammingTFConfig) TerraformBlock() *terraform.RootBlock {
 	return c.terraformBlock
 }
 
+// RootBlocks returns every root block of the Terraform module.
+func (c *MetaProgrammingTFConfig) RootBlocks() []*terraform.RootBlock {
+	return c.allRootBlocks
+}
+
 // ModuleDir returns the absolute directory of the Terraform module mapotf is
 // currently transforming. Used by data blocks that need to resolve paths
 // declared in the caller's `.mptf.hcl` (for example `data "module_source"`
@@ -130,7 +174,64 @@ func (c *MetaProgrammingTFConfig) ModuleDir() string {
 	return c.module.AbsDir
 }
 
+// RootBlock returns the block with the given address. `<address>@<file name>`
+// returns the block declared in that file: the block itself, or the block
+// overriding it in that override file, see terraform.OverrideAddress.
 func (c *MetaProgrammingTFConfig) RootBlock(address string) *terraform.RootBlock {
+	if baseAddress, fileName, ok := strings.Cut(address, "@"); ok {
+		b := c.rootBlock(baseAddress)
+		if b != nil && b.Range().Filename == fileName {
+			return b
+		}
+		return b.Override(fileName)
+	}
+	return c.rootBlock(address)
+}
+
+// TargetBlocks returns the root blocks value refers to: objects with an `mptf`
+// attribute, like the values of `data.resource.<name>.result.<type>`, and
+// block addresses. Collections are searched recursively, so a whole `result`
+// refers to every block in it.
+func (c *MetaProgrammingTFConfig) TargetBlocks(value cty.Value) ([]*terraform.RootBlock, error) {
+	if value.IsNull() {
+		return nil, nil
+	}
+	if !value.IsWhollyKnown() {
+		return nil, fmt.Errorf("value is not known")
+	}
+	t := value.Type()
+	address := ""
+	switch {
+	case t == cty.String:
+		address = value.AsString()
+	case t.IsObjectType() && t.HasAttribute("mptf"):
+		mptf := value.GetAttr("mptf")
+		if mptf.IsNull() || !mptf.Type().IsObjectType() || !mptf.Type().HasAttribute("block_address") || mptf.GetAttr("block_address").Type() != cty.String {
+			return nil, fmt.Errorf("invalid `mptf` object, `block_address` is missing")
+		}
+		address = mptf.GetAttr("block_address").AsString()
+	case value.CanIterateElements():
+		var r []*terraform.RootBlock
+		for it := value.ElementIterator(); it.Next(); {
+			_, v := it.Element()
+			blocks, err := c.TargetBlocks(v)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, blocks...)
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("value must be Terraform blocks or block addresses, got %s", t.FriendlyName())
+	}
+	b := c.RootBlock(address)
+	if b == nil {
+		return nil, fmt.Errorf("no block with address %s", address)
+	}
+	return []*terraform.RootBlock{b}, nil
+}
+
+func (c *MetaProgrammingTFConfig) rootBlock(address string) *terraform.RootBlock {
 	if strings.HasPrefix(address, "resource.") {
 		return c.resourceBlocks[address]
 	}
@@ -155,6 +256,18 @@ func (c *MetaProgrammingTFConfig) RootBlock(address string) *terraform.RootBlock
 	if strings.HasPrefix(address, "moved.") {
 		return c.movedBlocks[address]
 	}
+	if strings.HasPrefix(address, "provider.") {
+		return c.providerBlocks[address]
+	}
+	if strings.HasPrefix(address, "import.") {
+		return c.importBlocks[address]
+	}
+	if strings.HasPrefix(address, "removed.") {
+		return c.removedBlocks[address]
+	}
+	if strings.HasPrefix(address, "check.") {
+		return c.checkBlocks[address]
+	}
 	if address == "terraform" {
 		return c.terraformBlock
 	}
@@ -234,13 +347,23 @@ func ModuleRefs(tfDir string) ([]*TerraformModuleRef, error) {
 	if err = json.Unmarshal(manifestJson, &modules); err != nil {
 		return nil, fmt.Errorf("cannot unmarshal `modules.json` at %s: %+v", moduleManifest, err)
 	}
-	for i, m := range modules.Modules {
+	// Several module calls can share one local source. The directory is
+	// transformed once, by a ref that carries the keys of all of them.
+	var refs []*TerraformModuleRef
+	byAbsDir := make(map[string]*TerraformModuleRef)
+	for _, m := range modules.Modules {
 		if err := m.Load(); err != nil {
 			return nil, fmt.Errorf("cannot load info for %s: %+v", m.Dir, err)
 		}
-		modules.Modules[i] = m
+		if ref, ok := byAbsDir[m.AbsDir]; ok {
+			ref.Keys = append(ref.Keys, m.Key)
+			continue
+		}
+		m.Keys = []string{m.Key}
+		byAbsDir[m.AbsDir] = m
+		refs = append(refs, m)
 	}
-	return modules.Modules, nil
+	return refs, nil
 }
 
 func (c *MetaProgrammingTFConfig) slice(blocks map[string]*terraform.RootBlock) []*terraform.RootBlock {
@@ -251,6 +374,13 @@ func (c *MetaProgrammingTFConfig) slice(blocks map[string]*terraform.RootBlock)
 	return r
 }
 
+// hasBlock reports whether the module, including the blocks earlier
+// transforms added, already has a root block with the address of block, see
+// terraform.Module.HasBlock.
+func (c *MetaProgrammingTFConfig) hasBlock(block *hclwrite.Block) bool {
+	return c.module.HasBlock(block)
+}
+
 func (c *MetaProgrammingTFConfig) AddBlock(filename string, block *hclwrite.Block) {
 	c.module.AddBlock(filename, block)
 }
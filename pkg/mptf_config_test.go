@@ -24,7 +24,7 @@ func TestNewMetaProgrammingTFConfigShouldLoadTerraformBlocks(t *testing.T) {
 	sut, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	assert.NotEmpty(t, sut.ResourceBlocks)
 }
@@ -38,7 +38,7 @@ func TestNewMetaProgrammingTFConfigShouldLoadTerraformBlock(t *testing.T) {
 	sut, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	assert.NotNil(t, sut.TerraformBlock())
 }
@@ -66,7 +66,7 @@ func TestMetaProgrammingTFConfigBlocks(t *testing.T) {
 	sut, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, nil, nil, context.TODO())
+	}, nil, nil, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 
 	assert.NotEmpty(t, sut.ResourceBlocks(), "resourceBlocks should not be empty")
@@ -146,3 +146,35 @@ func assertCtyMapRawEquals(t *testing.T, expected, actual map[string]cty.Value)
 		t.Errorf("cty maps differ\nexpected: %s\n  actual: %s", e.GoString(), a.GoString())
 	}
 }
+
+func TestModuleRefs_SameDirectoryIsTransformedOnce(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		"/.terraform/modules/modules.json": `{
+			"Modules": [
+				{
+					"Key": "",
+					"Source": "",
+					"Dir": "."
+				},
+				{
+					"Key": "this",
+					"Source": "../../",
+					"Dir": "module"
+				},
+				{
+					"Key": "that",
+					"Source": "../../",
+					"Dir": "module"
+				}
+			]
+		}`,
+	}))
+	defer stub.Reset()
+
+	refs, err := pkg.ModuleRefs("/")
+	require.NoError(t, err)
+	require.Len(t, refs, 2)
+	assert.Equal(t, []string{""}, refs[0].Keys)
+	assert.Equal(t, "this", refs[1].Key)
+	assert.Equal(t, []string{"this", "that"}, refs[1].Keys)
+}
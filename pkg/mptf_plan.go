@@ -3,6 +3,9 @@ package pkg
 import (
 	"fmt"
 	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg/backup"
+	"github.com/Azure/mapotf/pkg/terraform"
+	"github.com/hashicorp/go-multierror"
 	"strings"
 )
 
@@ -15,13 +18,48 @@ func RunMetaProgrammingTFPlan(c *MetaProgrammingTFConfig) (*MetaProgrammingTFPla
 	plan := &MetaProgrammingTFPlan{
 		c: c,
 	}
-	plan.Transforms = append(plan.Transforms, golden.Blocks[Transform](c)...)
+	plan.Transforms = c.transformOrder.sort(golden.Blocks[Transform](c))
+	return plan, nil
+}
+
+// RunMetaProgrammingTFPhasePlan is RunMetaProgrammingTFPlan restricted to the
+// transforms of one phase. Running each phase against a config created after
+// the previous phase was saved lets its data blocks see the earlier changes.
+func RunMetaProgrammingTFPhasePlan(c *MetaProgrammingTFConfig, phase string) (*MetaProgrammingTFPlan, error) {
+	plan, err := RunMetaProgrammingTFPlan(c)
+	if err != nil {
+		return nil, err
+	}
+	var transforms []Transform
+	for _, t := range plan.Transforms {
+		if c.transformOrder.phaseOf(t) == phase {
+			transforms = append(transforms, t)
+		}
+	}
+	plan.Transforms = transforms
 	return plan, nil
 }
 
 type MetaProgrammingTFPlan struct {
-	c          *MetaProgrammingTFConfig
-	Transforms []Transform
+	c             *MetaProgrammingTFConfig
+	Transforms    []Transform
+	changedBlocks []terraform.BlockChange
+	results       []TransformResult
+	changesets    []backup.Changeset
+	conflicts     []Conflict
+	conflictMode  string
+}
+
+// TransformResult records what one transform did to the module during Apply.
+// TargetBlockAddresses lists the root blocks the transform modified, added or
+// removed, and ChangedFiles the module files whose rendered content changed.
+type TransformResult struct {
+	Address              string   `json:"address"`
+	Type                 string   `json:"type"`
+	TargetBlockAddresses []string `json:"target_block_addresses"`
+	ChangedFiles         []string `json:"changed_files"`
+	NoOp                 bool     `json:"no_op"`
+	Skipped              bool     `json:"skipped,omitempty"`
 }
 
 func (m *MetaProgrammingTFPlan) String() string {
@@ -39,6 +77,7 @@ func (m *MetaProgrammingTFPlan) Apply() error {
 	for _, transform := range m.Transforms {
 		addresses[transform.Address()] = struct{}{}
 	}
+	skipped := make(map[string]struct{})
 	if err = golden.Traverse[Transform](m.c.BaseConfig, func(b Transform) error {
 		if _, ok := addresses[b.Address()]; !ok {
 			return nil
@@ -46,6 +85,13 @@ func (m *MetaProgrammingTFPlan) Apply() error {
 		if err := golden.Decode(b); err != nil {
 			return fmt.Errorf("%s(%s) decode error: %+v", b.Address(), b.HclBlock().Range().String(), err)
 		}
+		enabled, err := transformEnabled(b, m.c.transformMeta[transformBlockAddress(b.HclBlock())]["condition"])
+		if err != nil {
+			return fmt.Errorf("%s(%s) condition error: %+v", b.Address(), b.HclBlock().Range().String(), err)
+		}
+		if !enabled {
+			skipped[b.Address()] = struct{}{}
+		}
 		return nil
 	}); err != nil {
 		return err
@@ -55,17 +101,122 @@ func (m *MetaProgrammingTFPlan) Apply() error {
 		return err
 	}
 
-	if err = golden.Traverse[Transform](m.c.BaseConfig, func(b Transform) error {
-		if _, ok := addresses[b.Address()]; !ok {
+	before := m.c.module.Snapshot()
+	last := before
+	m.results = nil
+	m.changesets = nil
+	m.conflicts = nil
+	tracker := newWriteTracker(m.c.transformOrder)
+	if err = m.applyInOrder(func(b Transform) error {
+		if _, ok := skipped[b.Address()]; ok {
+			m.results = append(m.results, newTransformResult(b, last, last))
+			m.results[len(m.results)-1].Skipped = true
 			return nil
 		}
-		return b.Apply()
+		if err := b.Apply(); err != nil {
+			return err
+		}
+		current := m.c.module.SnapshotSince(last)
+		m.results = append(m.results, newTransformResult(b, last, current))
+		previous := last
+		last = current
+		if m.conflictMode != ConflictModeOff {
+			for _, bc := range previous.ChangedBlocks(current) {
+				if after, ok := current.Blocks[bc.Address]; ok {
+					tracker.record(b, bc.Address, previous.Blocks[bc.Address], after)
+				}
+			}
+		}
+		changeset := backup.Changeset{
+			Transform: b.Address(),
+		}
+		for _, fn := range previous.ChangedFiles(current) {
+			if terraform.IsJSONFile(fn) {
+				return fmt.Errorf("%s(%s) cannot modify %s: JSON configuration files are read-only", b.Address(), b.HclBlock().Range().String(), fn)
+			}
+			changeset.Hunks = append(changeset.Hunks, backup.Diff(fn, previous.Files[fn], current.Files[fn])...)
+		}
+		if len(changeset.Hunks) > 0 {
+			m.changesets = append(m.changesets, changeset)
+		}
+		return nil
 	}); err != nil {
 		return fmt.Errorf("errors applying transforms: %+v", err)
 	}
+	m.changedBlocks = before.ChangedBlocks(last)
+	m.conflicts = tracker.conflicts
+	if m.conflictMode == ConflictModeError && len(m.conflicts) > 0 {
+		var conflicts []string
+		for _, c := range m.conflicts {
+			conflicts = append(conflicts, "  "+c.String())
+		}
+		return fmt.Errorf("transforms overwrite each other, add `depends_on` to order them on purpose:\n%s", strings.Join(conflicts, "\n"))
+	}
 	if err = m.c.SaveToDisk(); err != nil {
 		return fmt.Errorf("errors saving changes: %+v", err)
 	}
 
 	return nil
 }
+
+// applyInOrder calls apply for every transform of the plan, in the order of
+// Transforms, and aggregates the errors.
+func (m *MetaProgrammingTFPlan) applyInOrder(apply func(b Transform) error) error {
+	var err error
+	for _, t := range m.Transforms {
+		if subErr := apply(t); subErr != nil {
+			err = multierror.Append(err, subErr)
+		}
+	}
+	return err
+}
+
+// ChangedBlocks returns the Terraform root blocks the last Apply modified,
+// added or removed, sorted by address.
+func (m *MetaProgrammingTFPlan) ChangedBlocks() []terraform.BlockChange {
+	return m.changedBlocks
+}
+
+// SetConflictMode sets how Apply handles two transforms writing the same
+// attribute, see CheckConflictMode. A plan starts in ConflictModeWarn.
+func (m *MetaProgrammingTFPlan) SetConflictMode(mode string) error {
+	if err := CheckConflictMode(mode); err != nil {
+		return err
+	}
+	m.conflictMode = mode
+	return nil
+}
+
+// Conflicts returns the attributes a transform of the last Apply overwrote
+// after another transform it doesn't depend on wrote them, in the order they
+// were overwritten.
+func (m *MetaProgrammingTFPlan) Conflicts() []Conflict {
+	return m.conflicts
+}
+
+// Changesets returns the hunks each transform of the last Apply wrote, in the
+// order they were applied. Transforms that changed nothing are left out.
+func (m *MetaProgrammingTFPlan) Changesets() []backup.Changeset {
+	return m.changesets
+}
+
+// Results returns one TransformResult per transform the last Apply ran, in
+// the order they were applied.
+func (m *MetaProgrammingTFPlan) Results() []TransformResult {
+	return m.results
+}
+
+func newTransformResult(t Transform, before, after terraform.Snapshot) TransformResult {
+	r := TransformResult{
+		Address:              t.Address(),
+		Type:                 t.Type(),
+		TargetBlockAddresses: []string{},
+		ChangedFiles:         []string{},
+	}
+	for _, b := range before.ChangedBlocks(after) {
+		r.TargetBlockAddresses = append(r.TargetBlockAddresses, b.Address)
+	}
+	r.ChangedFiles = append(r.ChangedFiles, before.ChangedFiles(after)...)
+	r.NoOp = len(r.TargetBlockAddresses) == 0 && len(r.ChangedFiles) == 0
+	return r
+}
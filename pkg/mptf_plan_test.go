@@ -4,12 +4,15 @@ import (
 	"context"
 	"github.com/Azure/mapotf/pkg"
 	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/Azure/mapotf/pkg/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
 )
 
 func TestMetaProgrammingTFPlan_OnlyTransformThatHasTargetShouldBeInThePlan(t *testing.T) {
@@ -42,10 +45,268 @@ transform "update_in_place" fake_resource2 {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "terraform",
 		AbsDir: "terraform",
-	}, nil, hclBlocks, nil, context.TODO())
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 	require.NoError(t, err)
 	assert.Len(t, plan.Transforms, 1)
 	assert.Equal(t, "resource.fake_resource.this", plan.Transforms[0].(*pkg.UpdateInPlaceTransform).TargetBlockAddress)
 }
+
+func TestMetaProgrammingTFPlan_TransformOnJsonFileShouldFail(t *testing.T) {
+	jsonContent := `{"resource": {"fake_resource": {"json": {"tags": {}}}}}`
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf.json"): jsonContent,
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" this {
+}`,
+		filepath.Join("mptf", "main.mptf.hcl"): `data "resource" fake_resource {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" fake_resource {
+  for_each = data.resource.fake_resource.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  asraw {
+    id = "x"
+  }
+}
+`,
+	}))
+	defer stub.Reset()
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, "mptf")
+	require.NoError(t, err)
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "terraform",
+		AbsDir: "terraform",
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
+	require.NoError(t, err)
+	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+	require.NoError(t, err)
+	require.Len(t, plan.Transforms, 2)
+
+	err = plan.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transform.update_in_place.fake_resource[json]")
+	assert.Contains(t, err.Error(), "cannot modify main.tf.json: JSON configuration files are read-only")
+	assert.NotContains(t, err.Error(), "fake_resource[this]")
+	content, err := afero.ReadFile(filesystem.Fs, filepath.Join("terraform", "main.tf.json"))
+	require.NoError(t, err)
+	assert.Equal(t, jsonContent, string(content))
+}
+
+func TestMetaProgrammingTFPlan_TransformCanTargetOverrideBlock(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" "this" {
+  name = "base"
+}
+
+resource "fake_resource" "that" {
+  name = "base"
+}
+`,
+		filepath.Join("terraform", "override.tf"): `resource "fake_resource" "this" {
+  name = "override"
+}
+`,
+		filepath.Join("mptf", "main.mptf.hcl"): `data "resource" fake_resource {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" fake_resource {
+  for_each = data.resource.fake_resource.result.fake_resource
+  target_block_address = "${each.value.mptf.block_address}@${each.value.mptf.attribute_sources.name}"
+  asstring {
+    tags = "\"${each.value.name}\""
+  }
+}
+`,
+	}))
+	defer stub.Reset()
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, "mptf")
+	require.NoError(t, err)
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "terraform",
+		AbsDir: "terraform",
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{
+		LoadOptions: terraform.LoadOptions{IncludeOverrides: true},
+	}, context.TODO())
+	require.NoError(t, err)
+	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+	require.NoError(t, err)
+	require.NoError(t, plan.Apply())
+	require.NoError(t, cfg.SaveToDisk())
+
+	base, err := afero.ReadFile(filesystem.Fs, filepath.Join("terraform", "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(base), "tags"))
+	assert.Contains(t, string(base), `tags = "base"`)
+	override, err := afero.ReadFile(filesystem.Fs, filepath.Join("terraform", "override.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(override), `tags = "override"`)
+}
+
+func TestMetaProgrammingTFPlan_TransformWithFalseConditionShouldBeSkipped(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" "this" {
+}
+
+resource "fake_resource" "that" {
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+`,
+		filepath.Join("mptf", "main.mptf.hcl"): `data "resource" fake_resource {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" fake_resource {
+  for_each             = data.resource.fake_resource.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  condition            = !contains(keys(each.value), "lifecycle")
+  asstring {
+    lifecycle {
+      prevent_destroy = "true"
+    }
+  }
+}
+`,
+	}))
+	defer stub.Reset()
+	cfg, plan := runPlan(t)
+	require.NoError(t, plan.Apply())
+	require.NoError(t, cfg.SaveToDisk())
+
+	content, err := afero.ReadFile(filesystem.Fs, filepath.Join("terraform", "main.tf"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(content), "prevent_destroy"))
+	results := make(map[string]pkg.TransformResult)
+	for _, r := range plan.Results() {
+		results[r.Address] = r
+	}
+	assert.True(t, results["transform.update_in_place.fake_resource[that]"].Skipped)
+	assert.True(t, results["transform.update_in_place.fake_resource[that]"].NoOp)
+	assert.False(t, results["transform.update_in_place.fake_resource[this]"].Skipped)
+	assert.Equal(t, []string{"resource.fake_resource.this"}, results["transform.update_in_place.fake_resource[this]"].TargetBlockAddresses)
+}
+
+func TestMetaProgrammingTFPlan_NonBoolConditionShouldFail(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" "this" {
+}
+`,
+		filepath.Join("mptf", "main.mptf.hcl"): `transform "update_in_place" this {
+  target_block_address = "resource.fake_resource.this"
+  condition            = "yes"
+  asstring {
+    tags = "{}"
+  }
+}
+`,
+	}))
+	defer stub.Reset()
+	_, plan := runPlan(t)
+	err := plan.Apply()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "transform.update_in_place.this")
+	assert.Contains(t, err.Error(), "condition must be a known bool value")
+}
+
+func TestMetaProgrammingTFPlan_ConditionOutsideTransformShouldFail(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" "this" {
+}
+`,
+		filepath.Join("mptf", "main.mptf.hcl"): `data "resource" fake_resource {
+  resource_type = "fake_resource"
+  condition     = true
+}
+`,
+	}))
+	defer stub.Reset()
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, "mptf")
+	require.NoError(t, err)
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "terraform",
+		AbsDir: "terraform",
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
+	if err == nil {
+		_, err = pkg.RunMetaProgrammingTFPlan(cfg)
+	}
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `An argument named "condition" is not expected here`)
+}
+
+func TestMetaProgrammingTFPlan_PhaseOutsideTransformShouldFail(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" "this" {
+}
+`,
+		filepath.Join("mptf", "main.mptf.hcl"): `rule "this" {
+  message = "found"
+  targets = []
+  phase   = "first"
+}
+`,
+	}))
+	defer stub.Reset()
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, "mptf")
+	require.NoError(t, err)
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "terraform",
+		AbsDir: "terraform",
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
+	if err == nil {
+		_, err = pkg.RunMetaProgrammingTFPlan(cfg)
+	}
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `An argument named "phase" is not expected here`)
+}
+
+func TestMetaProgrammingTFPlan_FailedPreconditionShouldAbortPlan(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): `resource "fake_resource" "this" {
+}
+`,
+		filepath.Join("mptf", "main.mptf.hcl"): `data "resource" fake_resource {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" fake_resource {
+  for_each             = data.resource.fake_resource.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  precondition {
+    condition     = contains(keys(each.value), "tags")
+    error_message = "${each.value.mptf.block_address} must declare tags"
+  }
+  asstring {
+    tags = "{}"
+  }
+}
+`,
+	}))
+	defer stub.Reset()
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, "mptf")
+	require.NoError(t, err)
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "terraform",
+		AbsDir: "terraform",
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
+	require.NoError(t, err)
+	_, err = pkg.RunMetaProgrammingTFPlan(cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "resource.fake_resource.this must declare tags")
+}
+
+func runPlan(t *testing.T) (*pkg.MetaProgrammingTFConfig, *pkg.MetaProgrammingTFPlan) {
+	hclBlocks, err := pkg.LoadMPTFHclBlocks(false, "mptf")
+	require.NoError(t, err)
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "terraform",
+		AbsDir: "terraform",
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
+	require.NoError(t, err)
+	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+	require.NoError(t, err)
+	return cfg, plan
+}
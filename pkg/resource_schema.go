@@ -6,7 +6,6 @@ import (
 	"github.com/hashicorp/terraform-exec/tfexec"
 	tfjson "github.com/hashicorp/terraform-json"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -17,11 +16,14 @@ type TerraformProviderSchemaRetriever interface {
 }
 
 type TerraformCliProviderSchemaRetriever struct {
-	ctx context.Context
+	ctx             context.Context
+	terraformBinary string
 }
 
-func NewTerraformCliProviderSchemaRetriever(ctx context.Context) TerraformProviderSchemaRetriever {
-	return TerraformCliProviderSchemaRetriever{ctx: ctx}
+// NewTerraformCliProviderSchemaRetriever returns a retriever running the
+// terraformBinary CLI, see TerraformOptions.
+func NewTerraformCliProviderSchemaRetriever(ctx context.Context, terraformBinary string) TerraformProviderSchemaRetriever {
+	return TerraformCliProviderSchemaRetriever{ctx: ctx, terraformBinary: terraformBinary}
 }
 
 func (t TerraformCliProviderSchemaRetriever) Get(providerSource, versionConstraint string) (*tfjson.ProviderSchema, error) {
@@ -87,6 +89,10 @@ func lookupProviderSchema(schemas map[string]*tfjson.ProviderSchema, providerSou
 	if r, ok := schemas[src]; ok && r != nil {
 		return r, nil
 	}
+	// OpenTofu resolves short provider sources against its own registry.
+	if r, ok := schemas[fmt.Sprintf("registry.opentofu.org/%s", lowered)]; ok && r != nil {
+		return r, nil
+	}
 	// Fall back to a direct lookup on the lowercased source for providers
 	// whose schema key already includes a non-default hostname or is
 	// otherwise not prefixed with `registry.terraform.io/`.
@@ -97,21 +103,7 @@ func lookupProviderSchema(schemas map[string]*tfjson.ProviderSchema, providerSou
 }
 
 func (t TerraformCliProviderSchemaRetriever) getTerraformPath() (string, error) {
-	var cmd *exec.Cmd
-
-	if t.isWindows() {
-		cmd = exec.Command("where", "terraform")
-	} else {
-		cmd = exec.Command("which", "terraform")
-	}
-
-	out, err := cmd.Output()
-	if err != nil {
-		return "", err
-	}
-
-	path := strings.TrimSpace(string(out))
-	return path, nil
+	return findTerraformBinary(t.terraformBinary, t.isWindows())
 }
 
 func (t TerraformCliProviderSchemaRetriever) isWindows() bool {
@@ -20,7 +20,7 @@ func TestTerraformCliProviderSchemaRetriever_retrieveLocalProviderSchema(t *test
 	if _, err := exec.LookPath("terraform"); err != nil {
 		t.Skip("Skipping test because Terraform is not available on PATH")
 	}
-	sut := pkg.NewTerraformCliProviderSchemaRetriever(context.Background())
+	sut := pkg.NewTerraformCliProviderSchemaRetriever(context.Background(), "terraform")
 	schema, err := sut.Get("hashicorp/local", "2.5.1")
 	require.NoError(t, err)
 	assert.Contains(t, schema.ResourceSchemas, "local_file")
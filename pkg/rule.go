@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"sort"
+
+	"github.com/Azure/golden"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Severities of a rule.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+)
+
+var _ golden.PlanBlock = &RuleBlock{}
+
+// RuleBlock is a `rule "<name>"` block. Every Terraform block its `targets`
+// refer to violates the rule, and is reported by `mapotf lint` as a Finding
+// instead of being transformed.
+type RuleBlock struct {
+	*golden.BaseBlock
+
+	Severity string    `hcl:"severity,optional" default:"warning" validate:"oneof=error warning note"`
+	Message  string    `hcl:"message" validate:"required"`
+	Targets  cty.Value `hcl:"targets"`
+	findings []Finding
+}
+
+// Finding is a Terraform block that violates a rule. Range is the range of
+// the block, its file name relative to the module.
+type Finding struct {
+	Rule         string
+	Severity     string
+	Message      string
+	BlockAddress string
+	Range        hcl.Range
+}
+
+func (r *RuleBlock) Type() string {
+	return ""
+}
+
+func (r *RuleBlock) BlockType() string {
+	return "rule"
+}
+
+func (r *RuleBlock) AddressLength() int { return 2 }
+
+func (r *RuleBlock) CanExecutePrePlan() bool {
+	return false
+}
+
+func (r *RuleBlock) ExecuteDuringPlan() error {
+	blocks, err := r.Config().(*MetaProgrammingTFConfig).TargetBlocks(r.Targets)
+	if err != nil {
+		return err
+	}
+	r.findings = nil
+	for _, b := range blocks {
+		r.findings = append(r.findings, Finding{
+			Rule:         r.Name(),
+			Severity:     r.Severity,
+			Message:      r.Message,
+			BlockAddress: b.Address,
+			Range:        b.Range(),
+		})
+	}
+	return nil
+}
+
+// Findings returns the findings of every rule of the config, sorted by file
+// and position. A block a rule targets twice is reported once.
+func (c *MetaProgrammingTFConfig) Findings() []Finding {
+	var findings []Finding
+	seen := make(map[Finding]struct{})
+	for _, r := range golden.Blocks[*RuleBlock](c) {
+		for _, f := range r.findings {
+			if _, ok := seen[f]; ok {
+				continue
+			}
+			seen[f] = struct{}{}
+			findings = append(findings, f)
+		}
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Range.Filename != b.Range.Filename {
+			return a.Range.Filename < b.Range.Filename
+		}
+		if a.Range.Start.Byte != b.Range.Start.Byte {
+			return a.Range.Start.Byte < b.Range.Start.Byte
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Message < b.Message
+	})
+	return findings
+}
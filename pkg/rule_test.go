@@ -0,0 +1,214 @@
+package pkg_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/prashantv/gostub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ruleTerraformCode = `resource "fake_resource" "this" {
+  tags = {}
+}
+
+resource "fake_resource" "that" {
+}
+
+variable "location" {
+}
+`
+
+func TestRuleBlock_Findings(t *testing.T) {
+	cases := []struct {
+		desc     string
+		mptf     string
+		expected []pkg.Finding
+	}{
+		{
+			desc: "blocks matched by data",
+			mptf: `
+data "resource" fake {
+  resource_type = "fake_resource"
+}
+
+rule "untagged" {
+  severity = "error"
+  message  = "fake_resource must have tags"
+  targets  = [for r in data.resource.fake.result.fake_resource : r if !contains(keys(r), "tags")]
+}
+`,
+			expected: []pkg.Finding{
+				{
+					Rule:         "untagged",
+					Severity:     pkg.SeverityError,
+					Message:      "fake_resource must have tags",
+					BlockAddress: "resource.fake_resource.that",
+					Range: hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 5, Column: 1, Byte: 49},
+						End:      hcl.Pos{Line: 6, Column: 2, Byte: 84},
+					},
+				},
+			},
+		},
+		{
+			desc: "block addresses with default severity",
+			mptf: `
+rule "location" {
+  message = "avoid variables"
+  targets = ["variable.location"]
+}
+`,
+			expected: []pkg.Finding{
+				{
+					Rule:         "location",
+					Severity:     pkg.SeverityWarning,
+					Message:      "avoid variables",
+					BlockAddress: "variable.location",
+					Range: hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 8, Column: 1, Byte: 86},
+						End:      hcl.Pos{Line: 9, Column: 2, Byte: 109},
+					},
+				},
+			},
+		},
+		{
+			desc: "sorted by position and deduplicated",
+			mptf: `
+rule "all" {
+  severity = "note"
+  message  = "found"
+  targets  = ["resource.fake_resource.that", "resource.fake_resource.this", "resource.fake_resource.that"]
+}
+`,
+			expected: []pkg.Finding{
+				{
+					Rule:         "all",
+					Severity:     pkg.SeverityNote,
+					Message:      "found",
+					BlockAddress: "resource.fake_resource.this",
+					Range: hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 1, Column: 1, Byte: 0},
+						End:      hcl.Pos{Line: 3, Column: 2, Byte: 47},
+					},
+				},
+				{
+					Rule:         "all",
+					Severity:     pkg.SeverityNote,
+					Message:      "found",
+					BlockAddress: "resource.fake_resource.that",
+					Range: hcl.Range{
+						Filename: "main.tf",
+						Start:    hcl.Pos{Line: 5, Column: 1, Byte: 49},
+						End:      hcl.Pos{Line: 6, Column: 2, Byte: 84},
+					},
+				},
+			},
+		},
+		{
+			desc: "no targets",
+			mptf: `
+rule "none" {
+  message = "found"
+  targets = []
+}
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+				"/main.tf": ruleTerraformCode,
+			}))
+			defer stub.Reset()
+
+			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+				Dir:    "/",
+				AbsDir: "/",
+			}, nil, ruleHclBlocks(t, c.mptf), nil, pkg.TerraformOptions{}, context.TODO())
+			require.NoError(t, err)
+			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+			require.NoError(t, err)
+			assert.Empty(t, plan.Transforms)
+			assert.Equal(t, c.expected, cfg.Findings())
+		})
+	}
+}
+
+func TestRuleBlock_InvalidConfig(t *testing.T) {
+	cases := []struct {
+		desc string
+		mptf string
+		err  string
+	}{
+		{
+			desc: "unknown severity",
+			mptf: `
+rule "bad" {
+  severity = "fatal"
+  message  = "found"
+  targets  = []
+}
+`,
+			err: "Severity",
+		},
+		{
+			desc: "value that isn't a block",
+			mptf: `
+rule "bad" {
+  message = "found"
+  targets = [1]
+}
+`,
+			err: "value must be Terraform blocks or block addresses",
+		},
+		{
+			desc: "unknown address",
+			mptf: `
+rule "bad" {
+  message = "found"
+  targets = ["resource.fake_resource.missing"]
+}
+`,
+			err: "no block with address resource.fake_resource.missing",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+				"/main.tf": ruleTerraformCode,
+			}))
+			defer stub.Reset()
+
+			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+				Dir:    "/",
+				AbsDir: "/",
+			}, nil, ruleHclBlocks(t, c.mptf), nil, pkg.TerraformOptions{}, context.TODO())
+			if err == nil {
+				_, err = pkg.RunMetaProgrammingTFPlan(cfg)
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.err)
+		})
+	}
+}
+
+// ruleHclBlocks reads code like LoadMPTFHclBlocks does, which gives `rule`
+// blocks the empty type golden expects from blocks with a name only.
+func ruleHclBlocks(t *testing.T, code string) []*golden.HclBlock {
+	readFile, diag := hclsyntax.ParseConfig([]byte(code), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	writeFile, diag := hclwrite.ParseConfig([]byte(code), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	return golden.AsHclBlocks(readFile.Body.(*hclsyntax.Body).Blocks, writeFile.Body().Blocks())
+}
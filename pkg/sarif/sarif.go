@@ -0,0 +1,122 @@
+// Package sarif writes findings as SARIF 2.1.0 logs, the format code scanning
+// services such as GitHub code scanning ingest.
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Levels of a Result.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri,omitempty"`
+	Rules          []Rule `json:"rules,omitempty"`
+}
+
+// Rule describes what the results with its ID report.
+type Rule struct {
+	ID                   string         `json:"id"`
+	ShortDescription     *Message       `json:"shortDescription,omitempty"`
+	DefaultConfiguration *Configuration `json:"defaultConfiguration,omitempty"`
+}
+
+type Configuration struct {
+	Level string `json:"level"`
+}
+
+type Result struct {
+	RuleID    string     `json:"ruleId"`
+	Level     string     `json:"level,omitempty"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// Region is a 1-based range of lines and columns, the end column being
+// exclusive.
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// NewLog returns a log of one run of driver that found results. Results is
+// never null in the output, since an empty run still means nothing was found.
+func NewLog(driver Driver, results []Result) *Log {
+	if results == nil {
+		results = []Result{}
+	}
+	return &Log{
+		Schema:  Schema,
+		Version: Version,
+		Runs: []Run{
+			{
+				Tool:    Tool{Driver: driver},
+				Results: results,
+			},
+		},
+	}
+}
+
+// NewLocation locates a region of the file at path, which should be relative
+// to the root of the repository so results show up next to the code.
+func NewLocation(path string, region Region) Location {
+	return Location{
+		PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{URI: filepath.ToSlash(path)},
+			Region:           &region,
+		},
+	}
+}
+
+func (l *Log) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(l)
+}
@@ -0,0 +1,72 @@
+package sarif_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg/sarif"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog_Write(t *testing.T) {
+	log := sarif.NewLog(sarif.Driver{
+		Name: "mapotf",
+		Rules: []sarif.Rule{
+			{
+				ID:               "untagged",
+				ShortDescription: &sarif.Message{Text: "Resources without tags"},
+			},
+		},
+	}, []sarif.Result{
+		{
+			RuleID:    "untagged",
+			Level:     sarif.LevelNote,
+			Message:   sarif.Message{Text: "resource.fake_resource.this"},
+			Locations: []sarif.Location{sarif.NewLocation("modules/main.tf", sarif.Region{StartLine: 1, StartColumn: 1, EndLine: 3, EndColumn: 2})},
+		},
+	})
+
+	out := new(bytes.Buffer)
+	require.NoError(t, log.Write(out))
+	assert.JSONEq(t, `{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "mapotf",
+          "rules": [
+            {
+              "id": "untagged",
+              "shortDescription": {"text": "Resources without tags"}
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "untagged",
+          "level": "note",
+          "message": {"text": "resource.fake_resource.this"},
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {"uri": "modules/main.tf"},
+                "region": {"startLine": 1, "startColumn": 1, "endLine": 3, "endColumn": 2}
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}`, out.String())
+}
+
+func TestNewLog_EmptyResultsAreAnArray(t *testing.T) {
+	out := new(bytes.Buffer)
+	require.NoError(t, sarif.NewLog(sarif.Driver{Name: "mapotf"}, nil).Write(out))
+	assert.Contains(t, out.String(), `"results": []`)
+}
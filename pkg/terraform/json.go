@@ -0,0 +1,437 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// IsJSONFile reports whether fileName holds JSON configuration syntax
+// (`.tf.json` or `.tofu.json`). Blocks loaded from such files can be matched
+// like any other block, but they are read-only: SaveToDisk never writes them
+// back.
+func IsJSONFile(fileName string) bool {
+	return strings.HasSuffix(fileName, ".tf.json") || strings.HasSuffix(fileName, ".tofu.json")
+}
+
+// rootBlockLabelCounts is the number of labels each root block type takes in
+// JSON syntax, where labels are expressed as nested object keys.
+var rootBlockLabelCounts = map[string]int{
+	"resource":  2,
+	"data":      2,
+	"ephemeral": 2,
+	"module":    1,
+	"provider":  1,
+	"variable":  1,
+	"output":    1,
+	"check":     1,
+	"terraform": 0,
+	"locals":    0,
+	"moved":     0,
+	"import":    0,
+	"removed":   0,
+}
+
+// nestedBlockLabelCounts lists the nested blocks Terraform's JSON syntax
+// defines without a provider schema. Any other object-valued property is
+// translated as an object attribute.
+var nestedBlockLabelCounts = map[string]int{
+	"lifecycle":          0,
+	"provisioner":        1,
+	"connection":         0,
+	"dynamic":            1,
+	"content":            0,
+	"precondition":       0,
+	"postcondition":      0,
+	"backend":            1,
+	"cloud":              0,
+	"workspaces":         0,
+	"required_providers": 0,
+	"validation":         0,
+	"assert":             0,
+}
+
+// expressionProperties lists, per enclosing block type, the properties whose
+// JSON strings are bare expressions (references, keywords or type
+// constraints) rather than string templates.
+var expressionProperties = map[string]map[string]bool{
+	"resource":  {"provider": true},
+	"data":      {"provider": true},
+	"ephemeral": {"provider": true},
+	"module":    {"providers": true},
+	"lifecycle": {"ignore_changes": true, "replace_triggered_by": true},
+	"moved":     {"from": true, "to": true},
+	"removed":   {"from": true},
+	"import":    {"to": true, "provider": true},
+	"variable":  {"type": true},
+}
+
+type jsonMember struct {
+	key      string
+	keySpan  jsonSpan
+	value    any
+	valueEnd int
+}
+
+// jsonObject keeps members in source order so the translation, and the
+// synthetic addresses derived from it, are stable. span covers its braces.
+type jsonObject struct {
+	members []jsonMember
+	span    jsonSpan
+}
+
+// jsonSpan is a byte range of the JSON source.
+type jsonSpan struct {
+	start, end int
+}
+
+// jsonSource is the part of the JSON source a line of the native translation
+// was written from: the key and labels of a block header with the object of
+// its body, or the key and value of an attribute.
+type jsonSource struct {
+	key    jsonSpan
+	labels []jsonSpan
+	value  jsonSpan
+}
+
+// nativeWriter builds the native translation of a JSON file and remembers
+// the JSON source of every block header and attribute line it writes.
+type nativeWriter struct {
+	sb      strings.Builder
+	line    int
+	sources map[int]jsonSource
+}
+
+func (w *nativeWriter) WriteString(s string) {
+	w.sb.WriteString(s)
+	w.line += strings.Count(s, "\n")
+}
+
+func (w *nativeWriter) source(s jsonSource) {
+	w.sources[w.line] = s
+}
+
+// jsonToNative translates a Terraform JSON configuration file into equivalent
+// native syntax, so its blocks can be loaded through the same hclsyntax and
+// hclwrite parsers as `.tf` files. The returned jsonRanges maps the ranges of
+// the translation back to the JSON source.
+func jsonToNative(content []byte, filename string) ([]byte, *jsonRanges, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	root, _, err := decodeJSONValue(decoder, content)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse %s: %+v", filename, err)
+	}
+	obj, ok := root.(jsonObject)
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot parse %s: the root of a JSON configuration file must be an object", filename)
+	}
+	w := &nativeWriter{line: 1, sources: make(map[int]jsonSource)}
+	for _, m := range obj.members {
+		labelCount, ok := rootBlockLabelCounts[m.key]
+		if !ok {
+			continue
+		}
+		if err = writeJSONBlocks(w, m.key, m.keySpan, nil, labelCount, m.value, true); err != nil {
+			return nil, nil, fmt.Errorf("cannot parse %s: %+v", filename, err)
+		}
+	}
+	// Formatting only changes the spaces within lines, so the line numbers
+	// recorded by w still hold.
+	native := hclwrite.Format([]byte(strings.TrimRight(w.sb.String(), "\n") + "\n"))
+	return native, newJSONRanges(content, filename, w.sources), nil
+}
+
+// decodeJSONValue decodes the next value and returns the offset where it
+// starts in content.
+func decodeJSONValue(decoder *json.Decoder, content []byte) (any, int, error) {
+	start := skipJSONSeparators(content, int(decoder.InputOffset()))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, start, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, start, nil
+	}
+	switch delim {
+	case '{':
+		obj := jsonObject{}
+		for decoder.More() {
+			keyStart := skipJSONSeparators(content, int(decoder.InputOffset()))
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, start, err
+			}
+			keySpan := jsonSpan{start: keyStart, end: int(decoder.InputOffset())}
+			value, _, err := decodeJSONValue(decoder, content)
+			if err != nil {
+				return nil, start, err
+			}
+			obj.members = append(obj.members, jsonMember{
+				key:      keyToken.(string),
+				keySpan:  keySpan,
+				value:    value,
+				valueEnd: int(decoder.InputOffset()),
+			})
+		}
+		_, err = decoder.Token()
+		obj.span = jsonSpan{start: start, end: int(decoder.InputOffset())}
+		return obj, start, err
+	case '[':
+		arr := []any{}
+		for decoder.More() {
+			value, _, err := decodeJSONValue(decoder, content)
+			if err != nil {
+				return nil, start, err
+			}
+			arr = append(arr, value)
+		}
+		_, err = decoder.Token()
+		return arr, start, err
+	}
+	return nil, start, io.ErrUnexpectedEOF
+}
+
+// skipJSONSeparators returns the offset of the first token at or after
+// offset, past the whitespace, commas and colons the decoder hasn't consumed
+// yet.
+func skipJSONSeparators(content []byte, offset int) int {
+	for offset < len(content) {
+		switch content[offset] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// writeJSONBlocks writes the blocks of blockType described by value. While
+// labels remain to be read, object keys are labels; an array at any level
+// declares several blocks. Root blocks are separated by a blank line.
+func writeJSONBlocks(w *nativeWriter, blockType string, typeSpan jsonSpan, labels []jsonMember, remaining int, value any, root bool) error {
+	if arr, ok := value.([]any); ok {
+		for _, v := range arr {
+			if err := writeJSONBlocks(w, blockType, typeSpan, labels, remaining, v, root); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	obj, ok := value.(jsonObject)
+	if !ok {
+		return fmt.Errorf("%s block must be a JSON object", blockType)
+	}
+	if remaining > 0 {
+		for _, m := range obj.members {
+			if err := writeJSONBlocks(w, blockType, typeSpan, append(append([]jsonMember{}, labels...), m), remaining-1, m.value, root); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	source := jsonSource{key: typeSpan, value: obj.span}
+	for _, l := range labels {
+		source.labels = append(source.labels, l.keySpan)
+	}
+	w.source(source)
+	w.WriteString(blockType)
+	for _, l := range labels {
+		w.WriteString(" ")
+		w.WriteString(quoteTemplate(l.key))
+	}
+	w.WriteString(" {\n")
+	if err := writeJSONBody(w, blockType, obj); err != nil {
+		return err
+	}
+	w.WriteString("}\n")
+	if root {
+		w.WriteString("\n")
+	}
+	return nil
+}
+
+func writeJSONBody(w *nativeWriter, blockType string, obj jsonObject) error {
+	for _, m := range obj.members {
+		if strings.HasPrefix(m.key, "//") {
+			continue
+		}
+		if labelCount, ok := nestedBlockLabels(blockType, m.key); ok && isJSONBlockValue(m.value) {
+			if err := writeJSONBlocks(w, m.key, m.keySpan, nil, labelCount, m.value, false); err != nil {
+				return err
+			}
+			continue
+		}
+		asExpression := m.key == "depends_on" || expressionProperties[blockType][m.key]
+		w.source(jsonSource{key: m.keySpan, value: jsonSpan{start: m.keySpan.start, end: m.valueEnd}})
+		w.WriteString(m.key)
+		w.WriteString(" = ")
+		w.WriteString(jsonExpression(m.value, asExpression))
+		w.WriteString("\n")
+	}
+	return nil
+}
+
+func nestedBlockLabels(parentType, key string) (int, bool) {
+	if parentType == "check" && key == "data" {
+		return 2, true
+	}
+	labelCount, ok := nestedBlockLabelCounts[key]
+	return labelCount, ok
+}
+
+func isJSONBlockValue(value any) bool {
+	switch v := value.(type) {
+	case jsonObject:
+		return true
+	case []any:
+		for _, e := range v {
+			if _, ok := e.(jsonObject); !ok {
+				return false
+			}
+		}
+		return len(v) > 0
+	}
+	return false
+}
+
+// jsonExpression renders value as a native expression. JSON strings are
+// string templates, unless asExpression says they hold bare expressions.
+func jsonExpression(value any, asExpression bool) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case json.Number:
+		return v.String()
+	case string:
+		if asExpression {
+			return v
+		}
+		return quoteTemplate(v)
+	case []any:
+		elements := make([]string, 0, len(v))
+		for _, e := range v {
+			elements = append(elements, jsonExpression(e, asExpression))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case jsonObject:
+		sb := strings.Builder{}
+		sb.WriteString("{\n")
+		for _, m := range v.members {
+			sb.WriteString(quoteTemplate(m.key))
+			sb.WriteString(" = ")
+			sb.WriteString(jsonExpression(m.value, asExpression))
+			sb.WriteString("\n")
+		}
+		sb.WriteString("}")
+		return sb.String()
+	}
+	return "null"
+}
+
+var templateEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+)
+
+// quoteTemplate quotes s as a native string template. Interpolation
+// sequences are kept as they are, since JSON strings are templates too.
+func quoteTemplate(s string) string {
+	return `"` + templateEscaper.Replace(s) + `"`
+}
+
+// jsonRanges maps the ranges of a native translation back to the JSON file it
+// was translated from, line by line.
+type jsonRanges struct {
+	content    []byte
+	filename   string
+	lineStarts []int
+	sources    map[int]jsonSource
+}
+
+func newJSONRanges(content []byte, filename string, sources map[int]jsonSource) *jsonRanges {
+	lineStarts := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	return &jsonRanges{
+		content:    content,
+		filename:   filename,
+		lineStarts: lineStarts,
+		sources:    sources,
+	}
+}
+
+// remap points the blocks and attributes of body, parsed from the native
+// translation, at the JSON they were written from. A block starts at the key
+// declaring it, its innermost label when it has one, and ends at the closing
+// brace of its object; an attribute covers its key and value. Ranges within
+// expressions still point into the translation.
+func (r *jsonRanges) remap(body *hclsyntax.Body) {
+	for _, attr := range body.Attributes {
+		src, ok := r.sources[attr.SrcRange.Start.Line]
+		if !ok {
+			continue
+		}
+		attr.NameRange = r.rangeOf(src.key)
+		attr.EqualsRange = r.rangeOf(jsonSpan{start: src.key.end, end: src.key.end})
+		attr.SrcRange = r.rangeOf(src.value)
+	}
+	for _, b := range body.Blocks {
+		if src, ok := r.sources[b.TypeRange.Start.Line]; ok {
+			b.TypeRange = r.rangeOf(src.key)
+			b.LabelRanges = nil
+			for _, l := range src.labels {
+				b.LabelRanges = append(b.LabelRanges, r.rangeOf(l))
+			}
+			if len(src.labels) > 0 {
+				b.TypeRange = b.LabelRanges[len(b.LabelRanges)-1]
+			}
+			b.OpenBraceRange = r.rangeOf(jsonSpan{start: src.value.start, end: src.value.start + 1})
+			b.CloseBraceRange = r.rangeOf(jsonSpan{start: src.value.end - 1, end: src.value.end})
+			b.Body.SrcRange = r.rangeOf(src.value)
+			b.Body.EndRange = b.CloseBraceRange
+		}
+		r.remap(b.Body)
+	}
+}
+
+func (r *jsonRanges) rangeOf(s jsonSpan) hcl.Range {
+	return hcl.Range{
+		Filename: r.filename,
+		Start:    r.pos(s.start),
+		End:      r.pos(s.end),
+	}
+}
+
+func (r *jsonRanges) pos(offset int) hcl.Pos {
+	line := sort.Search(len(r.lineStarts), func(i int) bool {
+		return r.lineStarts[i] > offset
+	})
+	lineStart := r.lineStarts[line-1]
+	return hcl.Pos{
+		Line:   line,
+		Column: utf8.RuneCount(r.content[lineStart:offset]) + 1,
+		Byte:   offset,
+	}
+}
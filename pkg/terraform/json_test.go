@@ -0,0 +1,155 @@
+package terraform
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonToNative(t *testing.T) {
+	content := `{
+  "//": "generated by cdktf",
+  "resource": {
+    "azurerm_resource_group": {
+      "this": {
+        "name": "rg-${var.suffix}",
+        "location": "eastus",
+        "tags": {"env": "dev"},
+        "depends_on": ["azurerm_resource_group.base"],
+        "lifecycle": {
+          "ignore_changes": ["tags"]
+        }
+      }
+    }
+  },
+  "locals": {
+    "count": 2
+  },
+  "provider": {
+    "azurerm": [
+      {"features": {}},
+      {"alias": "secondary", "features": {}}
+    ]
+  },
+  "moved": [
+    {"from": "azurerm_resource_group.old", "to": "azurerm_resource_group.this"}
+  ]
+}`
+	native, _, err := jsonToNative([]byte(content), "main.tf.json")
+	require.NoError(t, err)
+	expected := `resource "azurerm_resource_group" "this" {
+  name     = "rg-${var.suffix}"
+  location = "eastus"
+  tags = {
+    "env" = "dev"
+  }
+  depends_on = [azurerm_resource_group.base]
+  lifecycle {
+    ignore_changes = [tags]
+  }
+}
+
+locals {
+  count = 2
+}
+
+provider "azurerm" {
+  features = {
+  }
+}
+
+provider "azurerm" {
+  alias = "secondary"
+  features = {
+  }
+}
+
+moved {
+  from = azurerm_resource_group.old
+  to   = azurerm_resource_group.this
+}
+`
+	assert.Equal(t, expected, string(native))
+}
+
+func TestJsonToNative_InvalidJson(t *testing.T) {
+	_, _, err := jsonToNative([]byte(`{"resource": `), "main.tf.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "main.tf.json")
+}
+
+func TestLoadModuleShouldLoadJsonFilesReadOnly(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	jsonContent := `{"resource": {"fake_resource": {"json": {"attr": "value"}}}}`
+	_ = afero.WriteFile(mockFs, "/main.tf.json", []byte(jsonContent), 0644)
+	_ = afero.WriteFile(mockFs, "/override.tf.json", []byte(`{"resource": {"fake_resource": {"json": {"attr": "override"}}}}`), 0644)
+	_ = afero.WriteFile(mockFs, "/main.tf", []byte(`resource "fake_resource" "hcl" {}
+`), 0644)
+
+	m, err := LoadModule(ModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, LoadOptions{})
+	require.NoError(t, err)
+	require.Len(t, m.ResourceBlocks, 2)
+	var jsonBlock *RootBlock
+	for _, b := range m.ResourceBlocks {
+		if b.Address == "resource.fake_resource.json" {
+			jsonBlock = b
+		}
+	}
+	require.NotNil(t, jsonBlock)
+	assert.Equal(t, "main.tf.json", jsonBlock.Range().Filename)
+
+	jsonBlock.SetAttributeRaw("attr", nil)
+	require.NoError(t, m.SaveToDisk())
+	content, err := afero.ReadFile(mockFs, "/main.tf.json")
+	require.NoError(t, err)
+	assert.Equal(t, jsonContent, string(content))
+	exists, err := afero.Exists(mockFs, "/main.tf.json"+".mptfnew")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLoadModuleShouldKeepJsonSourceRanges(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	_ = afero.WriteFile(mockFs, "/main.tf.json", []byte(`{
+  "resource": {
+    "fake_resource": {
+      "this": {
+        "name": "this",
+        "lifecycle": {
+          "ignore_changes": ["tags"]
+        }
+      }
+    }
+  }
+}
+`), 0644)
+
+	m, err := LoadModule(ModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, LoadOptions{})
+	require.NoError(t, err)
+	require.Len(t, m.ResourceBlocks, 1)
+	b := m.ResourceBlocks[0]
+	position := func(r hcl.Range) [4]int {
+		return [4]int{r.Start.Line, r.Start.Column, r.End.Line, r.End.Column}
+	}
+	assert.Equal(t, "main.tf.json", b.Range().Filename)
+	assert.Equal(t, [4]int{4, 7, 9, 8}, position(b.Range()))
+	assert.Equal(t, [4]int{5, 9, 5, 23}, position(b.Body.Attributes["name"].SrcRange))
+	require.Len(t, b.Body.Blocks, 1)
+	assert.Equal(t, [4]int{6, 9, 8, 10}, position(b.Body.Blocks[0].Range()))
+}
@@ -0,0 +1,19 @@
+package terraform
+
+// LoadOptions tunes which files LoadModule reads. The zero value is plain
+// Terraform behaviour: `.tf` and `.tf.json` files only.
+type LoadOptions struct {
+	// OpenTofu, when true, also loads `.tofu` and `.tofu.json` files. As in
+	// OpenTofu itself, `foo.tofu` takes precedence over `foo.tf` (and
+	// `foo.tofu.json` over `foo.tf.json`): the `.tf` file is ignored.
+	//
+	// Set via the `--opentofu` CLI flag, or auto-detected by the CLI.
+	OpenTofu bool
+	// IncludeOverrides, when true, also loads override files (`override.tf`,
+	// `*_override.tf` and their JSON variants). Their blocks are merged into
+	// the blocks they override instead of being loaded as blocks of their
+	// own, see RootBlock.Overrides.
+	//
+	// Set via the `--include-overrides` CLI flag.
+	IncludeOverrides bool
+}
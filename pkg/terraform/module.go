@@ -35,6 +35,10 @@ var wantedTypes = map[string]func(module *Module) *[]*RootBlock{
 	"variable": func(m *Module) *[]*RootBlock { return &m.Variables },
 	"output":   func(m *Module) *[]*RootBlock { return &m.Outputs },
 	"moved":    func(m *Module) *[]*RootBlock { return &m.MovedBlocks },
+	"provider": func(m *Module) *[]*RootBlock { return &m.ProviderBlocks },
+	"import":   func(m *Module) *[]*RootBlock { return &m.ImportBlocks },
+	"removed":  func(m *Module) *[]*RootBlock { return &m.RemovedBlocks },
+	"check":    func(m *Module) *[]*RootBlock { return &m.CheckBlocks },
 }
 
 type Module struct {
@@ -51,40 +55,70 @@ type Module struct {
 	Outputs         []*RootBlock
 	Locals          []*RootBlock
 	MovedBlocks     []*RootBlock
+	ProviderBlocks  []*RootBlock
+	ImportBlocks    []*RootBlock
+	RemovedBlocks   []*RootBlock
+	CheckBlocks     []*RootBlock
 	Key             string
 	Source          string
 	Version         string
 	GitHash         string
+	Keys            []string
+	loadOptions     LoadOptions
 }
 
 func (m *Module) loadConfig(cfg, filename string) error {
-	writeFile, diag := hclwrite.ParseConfig([]byte(cfg), filename, hcl.InitialPos)
-	if diag.HasErrors() {
-		return diag
-	}
-	readFile, diag := hclsyntax.ParseConfig([]byte(cfg), filename, hcl.InitialPos)
-	if diag.HasErrors() {
-		return diag
+	readBlocks, writeBlocks, err := m.parseConfig(cfg, filename)
+	if err != nil {
+		return err
 	}
-	m.writeFiles[filename] = writeFile
-	readBlocks := readFile.Body.(*hclsyntax.Body).Blocks
-	writeBlocks := writeFile.Body().Blocks()
 	for i, rb := range readBlocks {
 		if rb.Type == "locals" {
-			m.loadLocals(rb, writeBlocks[i])
+			m.Locals = append(m.Locals, newLocalBlocks(m, rb, writeBlocks[i])...)
 			continue
 		}
 		getter, want := wantedTypes[rb.Type]
 		if !want {
 			continue
 		}
-		hclBlock := NewBlock(m, rb, writeBlocks[i])
 		blocks := getter(m)
-		*blocks = append(*blocks, hclBlock)
+		*blocks = append(*blocks, newRootBlock(m, rb, writeBlocks[i]))
 	}
 	return nil
 }
 
+func (m *Module) parseConfig(cfg, filename string) ([]*hclsyntax.Block, []*hclwrite.Block, error) {
+	var ranges *jsonRanges
+	if IsJSONFile(filename) {
+		native, r, err := jsonToNative([]byte(cfg), filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		cfg, ranges = string(native), r
+	}
+	writeFile, diag := hclwrite.ParseConfig([]byte(cfg), filename, hcl.InitialPos)
+	if diag.HasErrors() {
+		return nil, nil, diag
+	}
+	readFile, diag := hclsyntax.ParseConfig([]byte(cfg), filename, hcl.InitialPos)
+	if diag.HasErrors() {
+		return nil, nil, diag
+	}
+	if ranges != nil {
+		ranges.remap(readFile.Body.(*hclsyntax.Body))
+	}
+	m.writeFiles[filename] = writeFile
+	return readFile.Body.(*hclsyntax.Body).Blocks, writeFile.Body().Blocks(), nil
+}
+
+func newRootBlock(m *Module, rb *hclsyntax.Block, wb *hclwrite.Block) *RootBlock {
+	hclBlock := NewBlock(m, rb, wb)
+	if rb.Type == "provider" && len(rb.Labels) > 0 {
+		hclBlock.Address = providerAddress(rb.Labels[0], ProviderAlias(rb))
+	}
+	return hclBlock
+}
+
 type ModuleRef struct {
 	Key     string `json:"Key"`
 	Source  string `json:"Source"`
@@ -92,57 +126,144 @@ type ModuleRef struct {
 	AbsDir  string
 	Version string `json:"Version"`
 	GitHash string
+	Keys    []string
 }
 
-func LoadModule(mr ModuleRef) (*Module, error) {
+// LoadModule reads the configuration files of mr, which ones is up to opts.
+func LoadModule(mr ModuleRef, opts LoadOptions) (*Module, error) {
 	files, err := afero.ReadDir(fs.Fs, mr.AbsDir)
 	if err != nil {
 		return nil, err
 	}
 	m := &Module{
-		Dir:        mr.Dir,
-		AbsDir:     mr.AbsDir,
-		writeFiles: make(map[string]*hclwrite.File),
-		lock:       &sync.Mutex{},
-		Key:        mr.Key,
-		Source:     mr.Source,
-		Version:    mr.Version,
-		GitHash:    mr.GitHash,
+		Dir:         mr.Dir,
+		AbsDir:      mr.AbsDir,
+		writeFiles:  make(map[string]*hclwrite.File),
+		lock:        &sync.Mutex{},
+		Key:         mr.Key,
+		Source:      mr.Source,
+		Version:     mr.Version,
+		GitHash:     mr.GitHash,
+		Keys:        mr.Keys,
+		loadOptions: opts,
 	}
 	// Stable iteration order makes the synthetic addresses we assign below
-	// (moved blocks) deterministic across runs.
+	// (moved, import and removed blocks) deterministic across runs.
 	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	fileNames := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		fileNames[f.Name()] = struct{}{}
+	}
+	// Override files are merged into the blocks they override, so they are
+	// loaded once every base block is known, in lexical order as Terraform
+	// does.
+	var overrideFiles []string
 	for _, f := range files {
-		if f.IsDir() {
+		if f.IsDir() || opts.shadowedByTofuFile(f.Name(), fileNames) {
 			continue
 		}
-		if !strings.HasSuffix(f.Name(), ".tf") || f.Name() == "override.tf" || strings.HasSuffix(f.Name(), "_override.tf") {
+		if opts.IncludeOverrides && opts.isOverrideFile(f.Name()) {
+			overrideFiles = append(overrideFiles, f.Name())
 			continue
 		}
-		n := filepath.Join(mr.AbsDir, f.Name())
-		content, err := afero.ReadFile(fs.Fs, n)
-		if err != nil {
-			return nil, err
+		if !opts.isConfigFile(f.Name()) {
+			continue
 		}
-		if err = m.loadConfig(string(content), f.Name()); err != nil {
+		if err = m.loadFile(f.Name(), m.loadConfig); err != nil {
 			return nil, err
 		}
 	}
-	// Moved blocks carry no native labels. Assign declaration-order synthetic
-	// labels so they have unique addresses ("moved.0", "moved.1", ...) and can
-	// be looked up via cfg.RootBlock(address).
-	for i, b := range m.MovedBlocks {
-		label := strconv.Itoa(i)
-		b.Labels = []string{label}
-		b.Address = "moved." + label
+	m.assignSyntheticLabels()
+	for _, fileName := range overrideFiles {
+		if err = m.loadFile(fileName, m.loadOverrideConfig); err != nil {
+			return nil, err
+		}
 	}
 	return m, nil
 }
 
+func (m *Module) loadFile(fileName string, load func(cfg, filename string) error) error {
+	content, err := afero.ReadFile(fs.Fs, filepath.Join(m.AbsDir, fileName))
+	if err != nil {
+		return err
+	}
+	return load(string(content), fileName)
+}
+
+// isConfigFile reports whether fileName is a configuration file LoadModule
+// should read: `.tf` or `.tf.json`, plus `.tofu` and `.tofu.json` in OpenTofu
+// mode, excluding override files.
+func (o LoadOptions) isConfigFile(fileName string) bool {
+	base, ok := o.configFileBase(fileName)
+	return ok && !isOverrideBase(base)
+}
+
+// isOverrideFile reports whether fileName is an override file: `override.tf`,
+// `*_override.tf` or their `.tf.json` (and, in OpenTofu mode, `.tofu`)
+// variants.
+func (o LoadOptions) isOverrideFile(fileName string) bool {
+	base, ok := o.configFileBase(fileName)
+	return ok && isOverrideBase(base)
+}
+
+func (o LoadOptions) configFileBase(fileName string) (string, bool) {
+	name := strings.TrimSuffix(fileName, ".json")
+	switch {
+	case strings.HasSuffix(name, ".tf"):
+		return strings.TrimSuffix(name, ".tf"), true
+	case o.OpenTofu && strings.HasSuffix(name, ".tofu"):
+		return strings.TrimSuffix(name, ".tofu"), true
+	}
+	return "", false
+}
+
+func isOverrideBase(base string) bool {
+	return base == "override" || strings.HasSuffix(base, "_override")
+}
+
+// shadowedByTofuFile reports whether fileName is a `.tf` or `.tf.json` file
+// OpenTofu ignores because a `.tofu` or `.tofu.json` file with the same name
+// exists next to it.
+func (o LoadOptions) shadowedByTofuFile(fileName string, fileNames map[string]struct{}) bool {
+	if !o.OpenTofu {
+		return false
+	}
+	var tofuName string
+	switch {
+	case strings.HasSuffix(fileName, ".tf"):
+		tofuName = strings.TrimSuffix(fileName, ".tf") + ".tofu"
+	case strings.HasSuffix(fileName, ".tf.json"):
+		tofuName = strings.TrimSuffix(fileName, ".tf.json") + ".tofu.json"
+	default:
+		return false
+	}
+	_, ok := fileNames[tofuName]
+	return ok
+}
+
+// assignSyntheticLabels gives blocks that carry no native labels (moved,
+// import and removed blocks) declaration-order synthetic labels so they have
+// unique addresses ("moved.0", "import.1", ...) and can be looked up via
+// cfg.RootBlock(address).
+func (m *Module) assignSyntheticLabels() {
+	for blockType := range syntheticLabelTypes {
+		for i, b := range *wantedTypes[blockType](m) {
+			label := strconv.Itoa(i)
+			b.Labels = []string{label}
+			b.Address = blockType + "." + label
+		}
+	}
+}
+
 func (m *Module) SaveToDisk() error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	for fn, wf := range m.writeFiles {
+		// JSON files are loaded as a native-syntax translation, writing that
+		// back would replace the user's JSON.
+		if IsJSONFile(fn) {
+			continue
+		}
 		absPath := filepath.Join(m.Dir, fn)
 		exist, err := afero.Exists(fs.Fs, absPath)
 		if err != nil {
@@ -155,8 +276,7 @@ func (m *Module) SaveToDisk() error {
 				return err
 			}
 		}
-		content := wf.Bytes()
-		err = afero.WriteFile(fs.Fs, absPath, normalizeFileWhitespace(hclwrite.Format(content)), 0644)
+		err = afero.WriteFile(fs.Fs, absPath, renderFile(wf), 0644)
 		if err != nil {
 			return err
 		}
@@ -216,6 +336,47 @@ func (m *Module) RemoveBlock(block *hclwrite.Block) {
 	}
 }
 
+// HasBlock reports whether a file other than an override file already has a
+// root block with the address of block. A `locals` block exists when all of
+// its local values do, and a block without labels, like `moved`, when one of
+// its type renders exactly like it.
+func (m *Module) HasBlock(block *hclwrite.Block) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	locals := make(map[string]struct{})
+	for name := range block.Body().Attributes() {
+		locals[name] = struct{}{}
+	}
+	address := writeBlockAddress(block)
+	byContent := block.Type() != "locals" && block.Type() != "provider" && len(block.Labels()) == 0
+	rendered := renderTokens(block.BuildTokens(nil))
+	for fn, wf := range m.writeFiles {
+		if m.loadOptions.isOverrideFile(fn) {
+			continue
+		}
+		lock.Lock(fn)
+		for _, b := range wf.Body().Blocks() {
+			switch {
+			case b.Type() != block.Type():
+			case b.Type() == "locals":
+				for name := range b.Body().Attributes() {
+					delete(locals, name)
+				}
+			case byContent:
+				if renderTokens(b.BuildTokens(nil)) == rendered {
+					lock.Unlock(fn)
+					return true
+				}
+			case writeBlockAddress(b) == address:
+				lock.Unlock(fn)
+				return true
+			}
+		}
+		lock.Unlock(fn)
+	}
+	return block.Type() == "locals" && len(locals) == 0
+}
+
 // Helper function to compare if two label slices are equal
 func labelsEqual(a, b []string) bool {
 	if len(a) != len(b) {
@@ -231,9 +392,12 @@ func labelsEqual(a, b []string) bool {
 	return true
 }
 
-func (m *Module) loadLocals(rb *hclsyntax.Block, wb *hclwrite.Block) {
+// newLocalBlocks splits a `locals` block into one `local.<name>` root block
+// per attribute.
+func newLocalBlocks(m *Module, rb *hclsyntax.Block, wb *hclwrite.Block) []*RootBlock {
+	var r []*RootBlock
 	for attrName, attr := range rb.Body.Attributes {
-		rootBlock := NewBlock(m, &hclsyntax.Block{
+		r = append(r, NewBlock(m, &hclsyntax.Block{
 			Type:   "local",
 			Labels: []string{attrName},
 			Body: &hclsyntax.Body{
@@ -248,9 +412,9 @@ func (m *Module) loadLocals(rb *hclsyntax.Block, wb *hclwrite.Block) {
 			LabelRanges:     rb.LabelRanges,
 			OpenBraceRange:  rb.OpenBraceRange,
 			CloseBraceRange: rb.CloseBraceRange,
-		}, wb)
-		m.Locals = append(m.Locals, rootBlock)
+		}, wb))
 	}
+	return r
 }
 
 func (m *Module) Blocks() []*RootBlock {
@@ -260,6 +424,8 @@ func (m *Module) Blocks() []*RootBlock {
 		Concat(linq.From(m.DataBlocks)).Concat(linq.From(m.EphemeralBlocks)).
 		Concat(linq.From(m.ResourceBlocks)).
 		Concat(linq.From(m.ModuleBlocks)).Concat(linq.From(m.MovedBlocks)).
+		Concat(linq.From(m.ProviderBlocks)).Concat(linq.From(m.ImportBlocks)).
+		Concat(linq.From(m.RemovedBlocks)).Concat(linq.From(m.CheckBlocks)).
 		ToSlice(&blocks)
 	return blocks
 }
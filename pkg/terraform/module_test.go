@@ -30,7 +30,7 @@ ephemeral "fake_ephemeral" that {}
 	sut, err := LoadModule(ModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	})
+	}, LoadOptions{})
 	require.NoError(t, err)
 	assert.Len(t, sut.ResourceBlocks, 2)
 	assert.Len(t, sut.DataBlocks, 2)
@@ -53,7 +53,7 @@ func TestModule_SaveToDisk(t *testing.T) {
 	m, err := LoadModule(ModuleRef{
 		Dir:    "tmp",
 		AbsDir: "tmp",
-	})
+	}, LoadOptions{})
 	require.NoError(t, err)
 
 	// Do some modification on the resource block's hclwrite.Block
@@ -89,7 +89,7 @@ func TestLoadModuleShouldLoadTerraformBlock(t *testing.T) {
 	sut, err := LoadModule(ModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	})
+	}, LoadOptions{})
 	require.NoError(t, err)
 	assert.Len(t, sut.TerraformBlocks, 1)
 	tb := sut.TerraformBlocks[0]
@@ -126,7 +126,7 @@ locals {
 	sut, err := LoadModule(ModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	})
+	}, LoadOptions{})
 	require.NoError(t, err)
 
 	// Verify that the local blocks are loaded correctly
@@ -147,6 +147,35 @@ locals {
 	assert.Equal(t, "value2", localVar2Value.AsString())
 }
 
+func TestLoadModuleShouldLoadProviderBlocksWithAliasAwareAddresses(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	_ = afero.WriteFile(mockFs, "/providers.tf", []byte(`
+provider "azurerm" {
+  features {}
+}
+
+provider "azurerm" {
+  alias = "secondary"
+  features {}
+}
+`), 0644)
+
+	sut, err := LoadModule(ModuleRef{
+		Dir:    ".",
+		AbsDir: "/",
+	}, LoadOptions{})
+	require.NoError(t, err)
+	require.Len(t, sut.ProviderBlocks, 2)
+	assert.Equal(t, "provider.azurerm", sut.ProviderBlocks[0].Address)
+	assert.Equal(t, "provider.azurerm.secondary", sut.ProviderBlocks[1].Address)
+	assert.Equal(t, []string{"azurerm"}, sut.ProviderBlocks[1].Labels)
+	snapshot := sut.Snapshot()
+	assert.Contains(t, snapshot.Blocks, "provider.azurerm")
+	assert.Contains(t, snapshot.Blocks, "provider.azurerm.secondary")
+}
+
 func TestLoadModuleShouldBypassOverrideFiles(t *testing.T) {
 	mockFs := afero.NewMemMapFs()
 	stub := gostub.Stub(&filesystem.Fs, mockFs)
@@ -161,7 +190,7 @@ func TestLoadModuleShouldBypassOverrideFiles(t *testing.T) {
 	sut, err := LoadModule(ModuleRef{
 		Dir:    ".",
 		AbsDir: "/",
-	})
+	}, LoadOptions{})
 	require.NoError(t, err)
 
 	// Verify that the override files are bypassed
@@ -169,6 +198,45 @@ func TestLoadModuleShouldBypassOverrideFiles(t *testing.T) {
 	assert.Equal(t, "this", sut.ResourceBlocks[0].Labels[1])
 }
 
+func TestLoadModuleShouldIgnoreTofuFilesOutsideOpenTofuMode(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	_ = afero.WriteFile(mockFs, "/main.tf", []byte(`resource "fake_resource" "tf" {}`), 0644)
+	_ = afero.WriteFile(mockFs, "/main.tofu", []byte(`resource "fake_resource" "tofu" {}`), 0644)
+
+	sut, err := LoadModule(ModuleRef{
+		Dir:    ".",
+		AbsDir: "/",
+	}, LoadOptions{})
+	require.NoError(t, err)
+	require.Len(t, sut.ResourceBlocks, 1)
+	assert.Equal(t, "tf", sut.ResourceBlocks[0].Labels[1])
+}
+
+func TestLoadModuleInOpenTofuModeShouldPreferTofuFiles(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	_ = afero.WriteFile(mockFs, "/main.tf", []byte(`resource "fake_resource" "shadowed" {}`), 0644)
+	_ = afero.WriteFile(mockFs, "/main.tofu", []byte(`resource "fake_resource" "main" {}`), 0644)
+	_ = afero.WriteFile(mockFs, "/extra.tf", []byte(`resource "fake_resource" "extra" {}`), 0644)
+	_ = afero.WriteFile(mockFs, "/json.tofu.json", []byte(`{"resource": {"fake_resource": {"json": {}}}}`), 0644)
+	_ = afero.WriteFile(mockFs, "/json.tf.json", []byte(`{"resource": {"fake_resource": {"shadowed_json": {}}}}`), 0644)
+	_ = afero.WriteFile(mockFs, "/main_override.tofu", []byte(`resource "fake_resource" "override" {}`), 0644)
+
+	sut, err := LoadModule(ModuleRef{
+		Dir:    ".",
+		AbsDir: "/",
+	}, LoadOptions{OpenTofu: true})
+	require.NoError(t, err)
+	var names []string
+	for _, b := range sut.ResourceBlocks {
+		names = append(names, b.Labels[1])
+	}
+	assert.ElementsMatch(t, []string{"main", "extra", "json"}, names)
+}
+
 func TestModule_AddBlock(t *testing.T) {
 	// Create a mock file system
 	mockFs := afero.NewMemMapFs()
@@ -286,7 +354,7 @@ resource "azurerm_resource_group" "last" {}`,
 			m, err := LoadModule(ModuleRef{
 				Dir:    "tmp",
 				AbsDir: "tmp",
-			})
+			}, LoadOptions{})
 			require.NoError(t, err)
 			blockToRemove := createResourceBlock(tc.blockToRemove[0], tc.blockToRemove[1], tc.blockToRemove[2])
 
@@ -376,3 +444,50 @@ func TestModule_AddBlockThenRemoveBlock_ByTypeAndLabels(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "", string(content))
 }
+
+func TestModule_HasBlock(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	_ = afero.WriteFile(mockFs, "/main.tf", []byte(`provider "aws" {
+  alias  = "west"
+  region = "us-west-2"
+}
+
+locals {
+  a = 1
+}
+
+moved {
+  from = fake_resource.old
+  to   = fake_resource.this
+}
+`), 0644)
+	m, err := LoadModule(ModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, LoadOptions{})
+	require.NoError(t, err)
+
+	aliased := hclwrite.NewBlock("provider", []string{"aws"})
+	aliased.Body().SetAttributeValue("alias", cty.StringVal("west"))
+	otherAlias := hclwrite.NewBlock("provider", []string{"aws"})
+	otherAlias.Body().SetAttributeValue("alias", cty.StringVal("east"))
+	local := hclwrite.NewBlock("locals", nil)
+	local.Body().SetAttributeValue("a", cty.NumberIntVal(2))
+	newLocal := hclwrite.NewBlock("locals", nil)
+	newLocal.Body().SetAttributeValue("a", cty.NumberIntVal(1))
+	newLocal.Body().SetAttributeValue("b", cty.NumberIntVal(1))
+	sameMoved := hclwrite.NewBlock("moved", nil)
+	sameMoved.Body().SetAttributeTraversal("from", hcl.Traversal{hcl.TraverseRoot{Name: "fake_resource"}, hcl.TraverseAttr{Name: "old"}})
+	sameMoved.Body().SetAttributeTraversal("to", hcl.Traversal{hcl.TraverseRoot{Name: "fake_resource"}, hcl.TraverseAttr{Name: "this"}})
+
+	assert.True(t, m.HasBlock(aliased))
+	assert.False(t, m.HasBlock(otherAlias))
+	assert.False(t, m.HasBlock(hclwrite.NewBlock("provider", []string{"aws"})))
+	assert.True(t, m.HasBlock(local))
+	assert.False(t, m.HasBlock(newLocal))
+	assert.True(t, m.HasBlock(sameMoved))
+	assert.False(t, m.HasBlock(hclwrite.NewBlock("moved", nil)))
+	assert.False(t, m.HasBlock(hclwrite.NewBlock("variable", []string{"name"})))
+}
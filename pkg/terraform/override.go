@@ -0,0 +1,143 @@
+package terraform
+
+import "fmt"
+
+// mergeableNestedBlocks lists the nested block types an override merges
+// argument by argument. An override replaces every other nested block type
+// wholesale: a `provisioner` block in an override file drops all the
+// provisioners of the base block.
+var mergeableNestedBlocks = map[string]bool{
+	"lifecycle":          true,
+	"required_providers": true,
+}
+
+// loadOverrideConfig loads an override file. Its blocks are not added to the
+// module as blocks of their own, each one is attached to the base block with
+// the same address, which then evaluates to the merged configuration.
+func (m *Module) loadOverrideConfig(cfg, filename string) error {
+	readBlocks, writeBlocks, err := m.parseConfig(cfg, filename)
+	if err != nil {
+		return err
+	}
+	var overrides []*RootBlock
+	for i, rb := range readBlocks {
+		if rb.Type == "locals" {
+			overrides = append(overrides, newLocalBlocks(m, rb, writeBlocks[i])...)
+			continue
+		}
+		if _, want := wantedTypes[rb.Type]; !want {
+			continue
+		}
+		if syntheticLabelTypes[rb.Type] {
+			return fmt.Errorf("%s: %s blocks cannot be overridden", rb.DefRange().String(), rb.Type)
+		}
+		overrides = append(overrides, newRootBlock(m, rb, writeBlocks[i]))
+	}
+	bases := make(map[string]*RootBlock)
+	for _, b := range m.Blocks() {
+		if _, ok := bases[b.Address]; !ok {
+			bases[b.Address] = b
+		}
+	}
+	for _, o := range overrides {
+		base, ok := bases[o.Address]
+		if !ok {
+			return fmt.Errorf("%s: missing base block %s for override", o.DefRange().String(), o.Address)
+		}
+		base.Overrides = append(base.Overrides, o)
+	}
+	return nil
+}
+
+// OverrideAddress returns the address a transform uses to target the block
+// with the given address in override file fileName rather than the base
+// block: `<address>@<file name>`.
+func OverrideAddress(address, fileName string) string {
+	return address + "@" + fileName
+}
+
+// Override returns the block overriding b declared in override file fileName,
+// or nil if there is none.
+func (b *RootBlock) Override(fileName string) *RootBlock {
+	if b == nil {
+		return nil
+	}
+	for _, o := range b.Overrides {
+		if o.Range().Filename == fileName {
+			return o
+		}
+	}
+	return nil
+}
+
+// OverrideFiles returns the names of the override files that override b, in
+// the order they are merged.
+func (b *RootBlock) OverrideFiles() []string {
+	var r []string
+	for _, o := range b.Overrides {
+		r = append(r, o.Range().Filename)
+	}
+	return r
+}
+
+// AttributeSources maps every attribute and nested block type of the merged
+// view of b to the name of the file that sets it: the base block's file, or
+// the last override file that overrides it.
+func (b *RootBlock) AttributeSources() map[string]string {
+	_, _, sources := b.mergedContent()
+	return sources
+}
+
+// mergedContent applies b's overrides, in order, on top of b following
+// Terraform's override rules: attributes in an override replace the
+// attribute with the same name, and nested blocks replace every nested block
+// of the same type, except the types in mergeableNestedBlocks whose
+// arguments are merged one by one.
+func (b *RootBlock) mergedContent() (map[string]*Attribute, NestedBlocks, map[string]string) {
+	fileName := b.Range().Filename
+	attributes := make(map[string]*Attribute, len(b.Attributes))
+	nestedBlocks := make(NestedBlocks, len(b.NestedBlocks))
+	sources := make(map[string]string)
+	for n, a := range b.Attributes {
+		attributes[n] = a
+		sources[n] = fileName
+	}
+	for t, nbs := range b.NestedBlocks {
+		nestedBlocks[t] = nbs
+		sources[t] = fileName
+	}
+	for _, o := range b.Overrides {
+		overrideFileName := o.Range().Filename
+		for n, a := range o.Attributes {
+			attributes[n] = a
+			sources[n] = overrideFileName
+		}
+		for t, nbs := range o.NestedBlocks {
+			if base := nestedBlocks[t]; mergeableNestedBlocks[t] && len(base) == 1 && len(nbs) == 1 {
+				nbs = []*NestedBlock{mergeNestedBlock(base[0], nbs[0])}
+			}
+			nestedBlocks[t] = nbs
+			sources[t] = overrideFileName
+		}
+	}
+	return attributes, nestedBlocks, sources
+}
+
+func mergeNestedBlock(base, override *NestedBlock) *NestedBlock {
+	merged := *base
+	merged.Attributes = make(map[string]*Attribute, len(base.Attributes)+len(override.Attributes))
+	for n, a := range base.Attributes {
+		merged.Attributes[n] = a
+	}
+	for n, a := range override.Attributes {
+		merged.Attributes[n] = a
+	}
+	merged.NestedBlocks = make(NestedBlocks, len(base.NestedBlocks)+len(override.NestedBlocks))
+	for t, nbs := range base.NestedBlocks {
+		merged.NestedBlocks[t] = nbs
+	}
+	for t, nbs := range override.NestedBlocks {
+		merged.NestedBlocks[t] = nbs
+	}
+	return &merged
+}
@@ -0,0 +1,114 @@
+package terraform
+
+import (
+	"testing"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func loadModuleWithOverrides(t *testing.T, files map[string]string) (*Module, error) {
+	mockFs := afero.NewMemMapFs()
+	for n, content := range files {
+		_ = afero.WriteFile(mockFs, "/"+n, []byte(content), 0644)
+	}
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	t.Cleanup(stub.Reset)
+	return LoadModule(ModuleRef{
+		Dir:    ".",
+		AbsDir: "/",
+	}, LoadOptions{IncludeOverrides: true})
+}
+
+func TestLoadModuleWithOverridesShouldMergeOverrideBlocks(t *testing.T) {
+	m, err := loadModuleWithOverrides(t, map[string]string{
+		"main.tf": `resource "fake_resource" "this" {
+  name = "base"
+  tags = {}
+  lifecycle {
+    create_before_destroy = true
+    ignore_changes        = [tags]
+  }
+  provisioner "local-exec" {
+    command = "base"
+  }
+}
+
+locals {
+  env = "dev"
+}
+`,
+		"override.tf": `resource "fake_resource" "this" {
+  name = "override"
+  lifecycle {
+    prevent_destroy = true
+  }
+}
+`,
+		"z_override.tf": `resource "fake_resource" "this" {
+  provisioner "local-exec" {
+    command = "override"
+  }
+}
+
+locals {
+  env = "prod"
+}
+`,
+	})
+	require.NoError(t, err)
+	require.Len(t, m.ResourceBlocks, 1)
+	b := m.ResourceBlocks[0]
+	assert.Equal(t, []string{"override.tf", "z_override.tf"}, b.OverrideFiles())
+	assert.Equal(t, map[string]string{
+		"name":        "override.tf",
+		"tags":        "main.tf",
+		"lifecycle":   "override.tf",
+		"provisioner": "z_override.tf",
+	}, b.AttributeSources())
+
+	v := b.EvalContext()
+	assert.Equal(t, cty.StringVal("override"), v.GetAttr("name"))
+	lifecycle := v.GetAttr("lifecycle").Index(cty.NumberIntVal(0))
+	assert.True(t, lifecycle.GetAttr("create_before_destroy").True())
+	assert.True(t, lifecycle.GetAttr("prevent_destroy").True())
+	assert.Equal(t, cty.StringVal("[tags]"), lifecycle.GetAttr("ignore_changes"))
+	provisioners := v.GetAttr("provisioner")
+	assert.Equal(t, 1, provisioners.LengthInt())
+	assert.Equal(t, cty.StringVal("override"), provisioners.Index(cty.NumberIntVal(0)).GetAttr("command"))
+	assert.Equal(t, cty.StringVal("z_override.tf"), v.GetAttr("mptf").GetAttr("attribute_sources").GetAttr("provisioner"))
+
+	require.Len(t, m.Locals, 1)
+	assert.Equal(t, cty.StringVal("prod"), m.Locals[0].EvalContext().GetAttr("env"))
+	assert.Equal(t, "z_override.tf", m.Locals[0].Override("z_override.tf").Range().Filename)
+	assert.Nil(t, m.Locals[0].Override("override.tf"))
+}
+
+func TestLoadModuleWithOverridesShouldFailWithoutBaseBlock(t *testing.T) {
+	_, err := loadModuleWithOverrides(t, map[string]string{
+		"main.tf":     `resource "fake_resource" "this" {}`,
+		"override.tf": `resource "fake_resource" "that" {}`,
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing base block resource.fake_resource.that for override")
+}
+
+func TestSnapshotShouldAddressOverrideBlocksByFile(t *testing.T) {
+	m, err := loadModuleWithOverrides(t, map[string]string{
+		"main.tf":     `resource "fake_resource" "this" {}`,
+		"override.tf": `resource "fake_resource" "this" {}`,
+	})
+	require.NoError(t, err)
+	before := m.Snapshot()
+	m.ResourceBlocks[0].Override("override.tf").WriteBody().SetAttributeValue("name", cty.StringVal("x"))
+	assert.Equal(t, []BlockChange{
+		{
+			Address:  "resource.fake_resource.this@override.tf",
+			FileName: "override.tf",
+		},
+	}, before.ChangedBlocks(m.Snapshot()))
+}
@@ -0,0 +1,50 @@
+package terraform
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// providerAddress returns the address of a provider configuration block:
+// `provider.<name>` for the default configuration and
+// `provider.<name>.<alias>` for an aliased one, so several configurations of
+// the same provider can be addressed individually.
+func providerAddress(name, alias string) string {
+	if alias == "" {
+		return "provider." + name
+	}
+	return "provider." + name + "." + alias
+}
+
+// ProviderAlias returns the literal `alias` of a provider block, or "" when
+// the block has no alias. Terraform only accepts a static string here, so
+// anything that does not evaluate without a context is treated as no alias.
+func ProviderAlias(rb *hclsyntax.Block) string {
+	attr, ok := rb.Body.Attributes["alias"]
+	if !ok {
+		return ""
+	}
+	return literalString(attr.Expr)
+}
+
+func providerAliasFromWriteBlock(wb *hclwrite.Block) string {
+	attr := wb.Body().GetAttribute("alias")
+	if attr == nil {
+		return ""
+	}
+	expr, diag := hclsyntax.ParseExpression(attr.Expr().BuildTokens(nil).Bytes(), "", hcl.InitialPos)
+	if diag.HasErrors() {
+		return ""
+	}
+	return literalString(expr)
+}
+
+func literalString(expr hclsyntax.Expression) string {
+	v, diag := expr.Value(nil)
+	if diag.HasErrors() || v.IsNull() || !v.IsKnown() || v.Type() != cty.String {
+		return ""
+	}
+	return v.AsString()
+}
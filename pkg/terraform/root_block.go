@@ -20,6 +20,7 @@ var RootBlockReflectionInformation = func(v map[string]cty.Value, b *RootBlock)
 		"dir":      cty.StringVal(""),
 		"abs_dir":  cty.StringVal(""),
 		"git_hash": cty.StringVal(""),
+		"keys":     cty.ListValEmpty(cty.String),
 	})
 	if b.module != nil {
 		moduleObj = cty.ObjectVal(map[string]cty.Value{
@@ -29,15 +30,26 @@ var RootBlockReflectionInformation = func(v map[string]cty.Value, b *RootBlock)
 			"dir":      cty.StringVal(b.module.Dir),
 			"abs_dir":  cty.StringVal(b.module.AbsDir),
 			"git_hash": cty.StringVal(b.module.GitHash),
+			"keys":     moduleKeys(b.module.Keys),
 		})
 	}
 	labels := golden.ToCtyValue(b.Labels)
+	sources := map[string]cty.Value{}
+	for n, fileName := range b.AttributeSources() {
+		sources[n] = cty.StringVal(fileName)
+	}
+	overrideFiles := cty.ListValEmpty(cty.String)
+	if files := b.OverrideFiles(); len(files) > 0 {
+		overrideFiles = golden.ToCtyValue(files)
+	}
 	v["mptf"] = cty.ObjectVal(map[string]cty.Value{
 		"block_address":     cty.StringVal(b.Address),
 		"terraform_address": cty.StringVal(blockAddressToRef(b.Address)),
 		"block_type":        cty.StringVal(b.Type),
 		"block_labels":      labels,
 		"module":            moduleObj,
+		"attribute_sources": cty.ObjectVal(sources),
+		"override_files":    overrideFiles,
 		"range": cty.ObjectVal(map[string]cty.Value{
 			"file_name":    cty.StringVal(b.Range().Filename),
 			"start_line":   cty.NumberIntVal(int64(b.Range().Start.Line)),
@@ -52,6 +64,10 @@ func blockAddressToRef(address string) string {
 	if strings.HasPrefix(address, "resource.") {
 		return strings.TrimPrefix(address, "resource.")
 	}
+	// Resources refer to provider configurations as `<name>` or `<name>.<alias>`.
+	if strings.HasPrefix(address, "provider.") {
+		return strings.TrimPrefix(address, "provider.")
+	}
 	return address
 }
 
@@ -63,9 +79,20 @@ type RootBlock struct {
 	ForEach      *Attribute
 	Attributes   map[string]*Attribute
 	NestedBlocks NestedBlocks
-	Type         string
-	Labels       []string
-	Address      string
+	// Overrides holds the blocks with the same address declared in override
+	// files, in merge order. Only populated when LoadOptions.IncludeOverrides
+	// is set.
+	Overrides []*RootBlock
+	Type      string
+	Labels    []string
+	Address   string
+}
+
+func moduleKeys(keys []string) cty.Value {
+	if len(keys) == 0 {
+		return cty.ListValEmpty(cty.String)
+	}
+	return golden.ToCtyValue(keys)
 }
 
 func (b *RootBlock) RemoveContent(path string) {
@@ -160,16 +187,13 @@ func NewBlock(m *Module, rb *hclsyntax.Block, wb *hclwrite.Block) *RootBlock {
 func (b *RootBlock) EvalContext() cty.Value {
 	v := map[string]cty.Value{}
 	RootBlockReflectionInformation(v, b)
-	for n, a := range b.Attributes {
+	// Attributes include `count` and `for_each`. With overrides loaded this is
+	// the merged view Terraform evaluates.
+	attributes, nestedBlocks, _ := b.mergedContent()
+	for n, a := range attributes {
 		v[n] = evalAttributeValue(a)
 	}
-	if b.Count != nil {
-		v["count"] = evalAttributeValue(b.Count)
-	}
-	if b.ForEach != nil {
-		v["for_each"] = evalAttributeValue(b.ForEach)
-	}
-	for k, values := range b.NestedBlocks.Values() {
+	for k, values := range nestedBlocks.Values() {
 		v[k] = values
 	}
 	return cty.ObjectVal(v)
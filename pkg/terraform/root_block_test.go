@@ -219,6 +219,7 @@ func TestReflectionObjectInEvalContext(t *testing.T) {
 		"dir":      cty.StringVal(""),
 		"abs_dir":  cty.StringVal(""),
 		"git_hash": cty.StringVal(""),
+		"keys":     cty.ListValEmpty(cty.String),
 	}), obj.GetAttr("module"))
 }
 
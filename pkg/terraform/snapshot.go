@@ -0,0 +1,194 @@
+package terraform
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Snapshot captures the in-memory state of a module at one point in time:
+// the content every file would have if SaveToDisk ran now, and the rendered
+// text of every root block keyed by block address. Comparing two snapshots
+// tells which files and which blocks a set of mutations touched, without
+// writing anything to disk.
+type Snapshot struct {
+	Files      map[string]string
+	Blocks     map[string]string
+	BlockFiles map[string]string
+	// files keeps the rendering of every file with the unformatted bytes it
+	// was rendered from, so SnapshotSince only renders the files changed
+	// since.
+	files map[string]*fileSnapshot
+}
+
+type fileSnapshot struct {
+	raw     string
+	content string
+	blocks  []snapshotBlock
+}
+
+// snapshotBlock is a rendered root block. Blocks addressed by position only
+// know their type until every file is assembled in order.
+type snapshotBlock struct {
+	address       string
+	syntheticType string
+	rendered      string
+}
+
+// BlockChange names a root block that differs between two snapshots and the
+// file it lives in (the file it was removed from, for a removed block).
+type BlockChange struct {
+	Address  string
+	FileName string
+}
+
+// Snapshot renders the module's current write-side files. Block addresses
+// follow the same scheme as LoadModule: `resource.<type>.<name>`,
+// `local.<name>` for each local value, alias-aware `provider.<name>[.<alias>]`
+// for provider configurations, declaration-order synthetic addresses
+// (`moved.0`, ...) for unlabeled blocks that need them, and
+// `<address>@<file name>` for blocks in override files.
+func (m *Module) Snapshot() Snapshot {
+	return m.SnapshotSince(Snapshot{})
+}
+
+// SnapshotSince is Snapshot, reusing the rendering of previous for every file
+// whose unformatted content hasn't changed since previous was taken. Taking
+// one after every mutation only costs the files the mutation touched.
+func (m *Module) SnapshotSince(previous Snapshot) Snapshot {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s := Snapshot{
+		Files:      make(map[string]string, len(m.writeFiles)),
+		Blocks:     make(map[string]string),
+		BlockFiles: make(map[string]string),
+		files:      make(map[string]*fileSnapshot, len(m.writeFiles)),
+	}
+	fileNames := make([]string, 0, len(m.writeFiles))
+	for fn := range m.writeFiles {
+		fileNames = append(fileNames, fn)
+	}
+	sort.Strings(fileNames)
+	synthetic := make(map[string]int)
+	for _, fn := range fileNames {
+		wf := m.writeFiles[fn]
+		lock.Lock(fn)
+		raw := string(wf.Bytes())
+		f, ok := previous.files[fn]
+		if !ok || f.raw != raw {
+			f = snapshotFile(raw, wf)
+		}
+		lock.Unlock(fn)
+		s.files[fn] = f
+		s.Files[fn] = f.content
+		for _, b := range f.blocks {
+			address := b.address
+			if b.syntheticType != "" {
+				address = b.syntheticType + "." + strconv.Itoa(synthetic[b.syntheticType])
+				synthetic[b.syntheticType]++
+			}
+			if m.loadOptions.isOverrideFile(fn) {
+				address = OverrideAddress(address, fn)
+			}
+			s.Blocks[address] = b.rendered
+			s.BlockFiles[address] = fn
+		}
+	}
+	return s
+}
+
+func snapshotFile(raw string, wf *hclwrite.File) *fileSnapshot {
+	f := &fileSnapshot{
+		raw:     raw,
+		content: string(renderFile(wf)),
+	}
+	for _, b := range wf.Body().Blocks() {
+		switch {
+		case b.Type() == "locals":
+			for name, attr := range b.Body().Attributes() {
+				f.blocks = append(f.blocks, snapshotBlock{address: "local." + name, rendered: renderTokens(attr.BuildTokens(nil))})
+			}
+		case syntheticLabelTypes[b.Type()]:
+			f.blocks = append(f.blocks, snapshotBlock{syntheticType: b.Type(), rendered: renderTokens(b.BuildTokens(nil))})
+		default:
+			f.blocks = append(f.blocks, snapshotBlock{address: writeBlockAddress(b), rendered: renderTokens(b.BuildTokens(nil))})
+		}
+	}
+	return f
+}
+
+// writeBlockAddress returns the address of a root block that is neither a
+// `locals` block nor addressed by position.
+func writeBlockAddress(b *hclwrite.Block) string {
+	if b.Type() == "provider" && len(b.Labels()) > 0 {
+		return providerAddress(b.Labels()[0], providerAliasFromWriteBlock(b))
+	}
+	return strings.Join(append([]string{b.Type()}, b.Labels()...), ".")
+}
+
+// ChangedFiles returns the sorted names of files whose content differs
+// between s and later, including files that only exist in later.
+func (s Snapshot) ChangedFiles(later Snapshot) []string {
+	return changedKeys(s.Files, later.Files)
+}
+
+// ChangedBlocks returns the root blocks that were modified, added or removed
+// between s and later, sorted by address.
+func (s Snapshot) ChangedBlocks(later Snapshot) []BlockChange {
+	var r []BlockChange
+	// A block moved to another file keeps its text but still changed.
+	addresses := changedKeys(s.Blocks, later.Blocks)
+	for _, address := range changedKeys(s.BlockFiles, later.BlockFiles) {
+		if _, ok := s.Blocks[address]; ok && s.Blocks[address] == later.Blocks[address] {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		fileName, ok := later.BlockFiles[address]
+		if !ok {
+			fileName = s.BlockFiles[address]
+		}
+		r = append(r, BlockChange{
+			Address:  address,
+			FileName: fileName,
+		})
+	}
+	return r
+}
+
+func changedKeys(before, after map[string]string) []string {
+	var r []string
+	for k, v := range after {
+		if old, ok := before[k]; !ok || old != v {
+			r = append(r, k)
+		}
+	}
+	for k := range before {
+		if _, ok := after[k]; !ok {
+			r = append(r, k)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+// syntheticLabelTypes lists the unlabeled root block types LoadModule assigns
+// declaration-order addresses to. `check` is not one of them: Terraform
+// requires a name label on every check block, so it is addressed natively as
+// `check.<name>`.
+var syntheticLabelTypes = map[string]bool{
+	"moved":   true,
+	"import":  true,
+	"removed": true,
+}
+
+func renderFile(wf *hclwrite.File) []byte {
+	return normalizeFileWhitespace(hclwrite.Format(wf.Bytes()))
+}
+
+func renderTokens(tokens hclwrite.Tokens) string {
+	return strings.TrimSpace(string(hclwrite.Format(tokens.Bytes())))
+}
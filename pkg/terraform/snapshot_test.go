@@ -0,0 +1,98 @@
+package terraform
+
+import (
+	"testing"
+
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestModule_SnapshotChangedBlocks(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	_ = afero.WriteFile(mockFs, "/main.tf", []byte(`resource "fake_resource" this {}
+resource "fake_resource" that {}
+
+locals {
+  a = 1
+  b = 2
+}
+
+moved {
+  from = fake_resource.old
+  to   = fake_resource.this
+}
+`), 0644)
+	_ = afero.WriteFile(mockFs, "/variables.tf", []byte(`variable "name" {}
+`), 0644)
+	m, err := LoadModule(ModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, LoadOptions{})
+	require.NoError(t, err)
+
+	before := m.Snapshot()
+	assert.Contains(t, before.Blocks, "resource.fake_resource.this")
+	assert.Contains(t, before.Blocks, "local.a")
+	assert.Contains(t, before.Blocks, "moved.0")
+	assert.Equal(t, "variables.tf", before.BlockFiles["variable.name"])
+
+	for _, rb := range m.ResourceBlocks {
+		if rb.Labels[1] == "this" {
+			rb.WriteBlock.Body().SetAttributeValue("tags", cty.EmptyObjectVal)
+		}
+	}
+	for _, lb := range m.Locals {
+		if lb.Labels[0] == "b" {
+			lb.WriteBlock.Body().SetAttributeValue("b", cty.NumberIntVal(3))
+		}
+	}
+	m.AddBlock("outputs.tf", hclwrite.NewBlock("output", []string{"name"}))
+
+	after := m.Snapshot()
+	assert.Equal(t, []string{"main.tf", "outputs.tf"}, before.ChangedFiles(after))
+	assert.Equal(t, []BlockChange{
+		{Address: "local.b", FileName: "main.tf"},
+		{Address: "output.name", FileName: "outputs.tf"},
+		{Address: "resource.fake_resource.this", FileName: "main.tf"},
+	}, before.ChangedBlocks(after))
+}
+
+func TestModule_SnapshotSinceRendersOnlyChangedFiles(t *testing.T) {
+	mockFs := afero.NewMemMapFs()
+	stub := gostub.Stub(&filesystem.Fs, mockFs)
+	defer stub.Reset()
+	_ = afero.WriteFile(mockFs, "/a.tf", []byte(`resource "fake_resource" this {}
+`), 0644)
+	_ = afero.WriteFile(mockFs, "/b.tf", []byte(`moved {
+  from = fake_resource.old
+  to   = fake_resource.this
+}
+`), 0644)
+	m, err := LoadModule(ModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, LoadOptions{})
+	require.NoError(t, err)
+
+	before := m.Snapshot()
+	moved := hclwrite.NewBlock("moved", nil)
+	moved.Body().SetAttributeTraversal("from", hcl.Traversal{hcl.TraverseRoot{Name: "fake_resource"}, hcl.TraverseAttr{Name: "older"}})
+	m.AddBlock("a.tf", moved)
+
+	after := m.SnapshotSince(before)
+	full := m.Snapshot()
+	assert.Equal(t, full.Files, after.Files)
+	assert.Equal(t, full.Blocks, after.Blocks)
+	assert.Equal(t, full.BlockFiles, after.BlockFiles)
+	assert.Equal(t, "b.tf", after.BlockFiles["moved.1"])
+	assert.Same(t, before.files["b.tf"], after.files["b.tf"])
+	assert.NotSame(t, before.files["a.tf"], after.files["a.tf"])
+}
@@ -0,0 +1,56 @@
+package pkg
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg/terraform"
+)
+
+// TerraformOptions are the Terraform flavour a MetaProgrammingTFConfig works
+// with. The zero value is plain Terraform.
+type TerraformOptions struct {
+	// Binary is the CLI mapotf shells out to for wrapped commands, provider
+	// schemas and remote module downloads: "terraform" when empty, or "tofu"
+	// in OpenTofu mode.
+	Binary string
+	// LoadOptions picks the files the Terraform module is loaded from.
+	LoadOptions terraform.LoadOptions
+}
+
+// BinaryName returns Binary, or "terraform" when it is empty.
+func (o TerraformOptions) BinaryName() string {
+	if o.Binary == "" {
+		return "terraform"
+	}
+	return o.Binary
+}
+
+// blockTerraformBinary returns the CLI of the config b belongs to, or
+// "terraform" for a block that doesn't belong to one.
+func blockTerraformBinary(b *golden.BaseBlock) string {
+	if b != nil {
+		if cfg, ok := b.Config().(*MetaProgrammingTFConfig); ok {
+			return cfg.terraformOptions.BinaryName()
+		}
+	}
+	return TerraformOptions{}.BinaryName()
+}
+
+// findTerraformBinary resolves the CLI name to an absolute path via `which`
+// (or `where` on Windows).
+func findTerraformBinary(name string, isWindows bool) (string, error) {
+	var cmd *exec.Cmd
+	if isWindows {
+		cmd = exec.Command("where", name)
+	} else {
+		cmd = exec.Command("which", name)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	// `where` lists every match, one per line.
+	return strings.TrimSpace(strings.Split(string(out), "\n")[0]), nil
+}
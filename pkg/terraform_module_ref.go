@@ -17,6 +17,9 @@ type TerraformModuleRef struct {
 	AbsDir  string
 	Version string `json:"Version"`
 	GitHash string
+	// Keys lists the keys of every module call that resolves to AbsDir, in
+	// `modules.json` order. Key is the first of them.
+	Keys []string `json:"-"`
 }
 
 func NewTerraformRootModuleRef(dir string) (*TerraformModuleRef, error) {
@@ -71,9 +74,17 @@ func (r *TerraformModuleRef) toTerraformPkgType() terraform.ModuleRef {
 		AbsDir:  r.AbsDir,
 		Version: r.Version,
 		GitHash: r.GitHash,
+		Keys:    r.keys(),
 	}
 }
 
+func (r *TerraformModuleRef) keys() []string {
+	if len(r.Keys) == 0 {
+		return []string{r.Key}
+	}
+	return r.Keys
+}
+
 func gitHash(dir string) (string, error) {
 	gitPath, err := lookupGitPath(dir)
 	if err != nil {
@@ -3,6 +3,7 @@ package pkg
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/Azure/golden"
 	"github.com/Azure/mapotf/pkg/terraform"
@@ -17,12 +18,19 @@ type AppendBlockBodyTransform struct {
 	*BaseTransform
 	TargetBlockAddress string `hcl:"target_block_address" validate:"required"`
 	BlockBody          string `hcl:"block_body" validate:"required"`
+	SkipIfExists       bool   `hcl:"skip_if_exists,optional"`
 }
 
 func (u *AppendBlockBodyTransform) Type() string {
 	return "append_block_body"
 }
 
+// Idempotent is only true with `skip_if_exists`, otherwise every run appends
+// the nested blocks of block_body again.
+func (u *AppendBlockBodyTransform) Idempotent() bool {
+	return u.SkipIfExists
+}
+
 func (u *AppendBlockBodyTransform) Apply() error {
 	c := u.Config().(*MetaProgrammingTFConfig)
 	b := c.RootBlock(u.TargetBlockAddress)
@@ -46,15 +54,33 @@ func (u *AppendBlockBodyTransform) PatchWriteBlock(dest terraform.Block, patch *
 		dest.SetAttributeRaw(name, attr.Expr().BuildTokens(nil))
 	}
 	for _, nb := range patch.Blocks() {
+		if u.SkipIfExists && hasIdenticalBlock(dest.WriteBody(), nb) {
+			continue
+		}
 		dest.AppendBlock(nb)
 	}
 }
 
+func hasIdenticalBlock(body *hclwrite.Body, block *hclwrite.Block) bool {
+	rendered := renderWriteBlock(block)
+	for _, b := range body.Blocks() {
+		if renderWriteBlock(b) == rendered {
+			return true
+		}
+	}
+	return false
+}
+
+func renderWriteBlock(block *hclwrite.Block) string {
+	return strings.TrimSpace(string(hclwrite.Format(block.BuildTokens(nil).Bytes())))
+}
+
 func (u *AppendBlockBodyTransform) String() string {
 	content := make(map[string]any)
 	content["id"] = u.Id()
 	content["target_block_address"] = u.TargetBlockAddress
 	content["concat"] = u.BlockBody
+	content["skip_if_exists"] = u.SkipIfExists
 	str, err := json.Marshal(content)
 	if err != nil {
 		panic(err.Error())
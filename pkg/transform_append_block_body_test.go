@@ -84,7 +84,7 @@ resource "fake_resource" this {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+			}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 			require.NoError(t, err)
@@ -97,3 +97,47 @@ resource "fake_resource" this {
 		})
 	}
 }
+
+func TestConcatBlockBodyTransform_SkipIfExistsShouldNotAppendExistingNestedBlock(t *testing.T) {
+	cfg := `
+transform "append_block_body" this {
+	target_block_address = "resource.fake_resource.this"
+	block_body = "tags = null\n nested_block {\n id = 123\n }\n nested_block {\n id = 456\n }"
+	skip_if_exists = true
+}
+`
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		"/main.tf": `
+resource "fake_resource" this {
+  tags = null
+  nested_block {
+    id = 123
+  }
+}`,
+	}))
+	defer stub.Reset()
+	readFile, diag := hclsyntax.ParseConfig([]byte(cfg), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	writeFile, diag := hclwrite.ParseConfig([]byte(cfg), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	hclBlock := golden.NewHclBlock(readFile.Body.(*hclsyntax.Body).Blocks[0], writeFile.Body().Blocks()[0], nil)
+	c, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
+	require.NoError(t, err)
+	plan, err := pkg.RunMetaProgrammingTFPlan(c)
+	require.NoError(t, err)
+	require.NoError(t, plan.Apply())
+	tfFile, err := afero.ReadFile(filesystem.Fs, "/main.tf")
+	require.NoError(t, err)
+	assert.Equal(t, formatHcl(`resource "fake_resource" this {
+  tags = null
+  nested_block {
+    id = 123
+  }
+  nested_block {
+    id = 456
+  }
+}`), formatHcl(string(tfFile)))
+}
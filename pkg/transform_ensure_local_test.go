@@ -94,7 +94,7 @@ func TestTransformEnsureLocal(t *testing.T) {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, hclBlocks, nil, context.TODO())
+			}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 			require.NoError(t, err)
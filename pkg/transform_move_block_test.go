@@ -231,7 +231,7 @@ resource "fake_resource" "this" {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+			}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 			require.NoError(t, err)
@@ -34,6 +34,7 @@ type NewBlockTransform struct {
 	FileName      string   `hcl:"filename" validate:"endswith=.tf"`
 	Labels        []string `hcl:"labels,optional"`
 	NewBody       string   `hcl:"body,optional"`
+	SkipIfExists  bool     `hcl:"skip_if_exists,optional"`
 	newWriteBlock *hclwrite.Block
 }
 
@@ -66,6 +67,11 @@ func (n *NewBlockTransform) Decode(block *golden.HclBlock, context *hcl.EvalCont
 	if bodyStr != nil {
 		n.NewBody = *bodyStr
 	}
+	skipIfExists, err := getOptionalBoolAttribute("skip_if_exists", block, context)
+	if err != nil {
+		return err
+	}
+	n.SkipIfExists = skipIfExists
 	n.newWriteBlock = hclwrite.NewBlock(n.NewBlockType, n.Labels)
 	decodeByNestedBlock := false
 	for _, b := range block.NestedBlocks() {
@@ -107,8 +113,18 @@ func (n *NewBlockTransform) Type() string {
 	return "new_block"
 }
 
+// Idempotent is only true with `skip_if_exists`, otherwise every run adds the
+// block again.
+func (n *NewBlockTransform) Idempotent() bool {
+	return n.SkipIfExists
+}
+
 func (n *NewBlockTransform) Apply() error {
-	n.Config().(*MetaProgrammingTFConfig).AddBlock(n.FileName, n.newWriteBlock)
+	c := n.Config().(*MetaProgrammingTFConfig)
+	if n.SkipIfExists && c.hasBlock(n.newWriteBlock) {
+		return nil
+	}
+	c.AddBlock(n.FileName, n.newWriteBlock)
 	return nil
 }
 
@@ -226,3 +242,21 @@ func getOptionalStringAttribute(name string, block *golden.HclBlock, context *hc
 	asString := v.AsString()
 	return &asString, nil
 }
+
+func getOptionalBoolAttribute(name string, block *golden.HclBlock, context *hcl.EvalContext) (bool, error) {
+	attr, ok := block.Attributes()[name]
+	if !ok {
+		return false, nil
+	}
+	v, err := attr.Value(context)
+	if err != nil {
+		return false, err
+	}
+	if v.IsNull() || v.Type() != cty.Bool {
+		return false, fmt.Errorf("`%s` must be a bool", name)
+	}
+	if !v.IsKnown() {
+		return false, fmt.Errorf("`%s` must be known", name)
+	}
+	return v.True(), nil
+}
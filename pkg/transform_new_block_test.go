@@ -168,7 +168,7 @@ func TestNewBlockTransform_NewBlockWithForEach(t *testing.T) {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+	}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 	require.NoError(t, err)
@@ -291,7 +291,7 @@ func TestNewBlockTransform_FormatVariableBlock(t *testing.T) {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+			}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 			require.NoError(t, err)
@@ -350,7 +350,7 @@ func TestNewBlockTransform_MalformedNewBlockShouldNotBlockSave(t *testing.T) {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+	}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 	require.NoError(t, err)
@@ -365,3 +365,97 @@ func TestNewBlockTransform_MalformedNewBlockShouldNotBlockSave(t *testing.T) {
 	actual := string(after)
 	assert.Equal(t, expected, actual)
 }
+
+func TestNewBlockTransform_SkipIfExists(t *testing.T) {
+	existing := `variable "test" {
+  type = string
+}
+`
+	code := `transform "new_block" existing {
+	new_block_type = "variable"
+	filename = "variables.tf"
+	labels = ["test"]
+	skip_if_exists = true
+	asraw {
+	  type = number
+	}
+}
+
+transform "new_block" first {
+	new_block_type = "variable"
+	filename = "variables.tf"
+	labels = ["new"]
+	skip_if_exists = true
+	asraw {
+	  type = string
+	}
+}
+
+transform "new_block" second {
+	new_block_type = "variable"
+	filename = "variables.tf"
+	labels = ["new"]
+	skip_if_exists = true
+	asraw {
+	  type = string
+	}
+}`
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		"/variables.tf": existing,
+	}))
+	defer stub.Reset()
+
+	readFile, diag := hclsyntax.ParseConfig([]byte(code), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	writeFile, diag := hclwrite.ParseConfig([]byte(code), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	var hclBlocks []*golden.HclBlock
+	for i, b := range readFile.Body.(*hclsyntax.Body).Blocks {
+		hclBlocks = append(hclBlocks, golden.NewHclBlock(b, writeFile.Body().Blocks()[i], nil))
+	}
+	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+		Dir:    "/",
+		AbsDir: "/",
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
+	require.NoError(t, err)
+	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
+	require.NoError(t, err)
+	for _, transform := range plan.Transforms {
+		assert.True(t, transform.Idempotent())
+	}
+	require.NoError(t, plan.Apply())
+	after, err := afero.ReadFile(filesystem.Fs, "/variables.tf")
+	require.NoError(t, err)
+	assert.Equal(t, `variable "test" {
+  type = string
+}
+
+variable "new" {
+  type = string
+}
+`, string(after))
+}
+
+func TestNewBlockTransform_UnknownSkipIfExistsIsAnError(t *testing.T) {
+	code := `transform "new_block" this {
+	new_block_type = "variable"
+	filename = "variables.tf"
+	labels = ["test"]
+	skip_if_exists = var.skip
+}`
+	readFile, diag := hclsyntax.ParseConfig([]byte(code), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	writeFile, diag := hclwrite.ParseConfig([]byte(code), "test.hcl", hcl.InitialPos)
+	require.Falsef(t, diag.HasErrors(), diag.Error())
+	hclBlock := golden.NewHclBlock(readFile.Body.(*hclsyntax.Body).Blocks[0], writeFile.Body().Blocks()[0], nil)
+
+	err := (&pkg.NewBlockTransform{}).Decode(hclBlock, &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(map[string]cty.Value{
+				"skip": cty.UnknownVal(cty.Bool),
+			}),
+		},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "`skip_if_exists` must be known")
+}
@@ -0,0 +1,263 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Azure/golden"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// defaultPhase is the phase of transforms without a `phase` meta-argument.
+const defaultPhase = "default"
+
+// transformOrder decides the order transforms are applied in. Phases run in
+// the order they are first declared, in file name then source order. Inside a
+// phase a transform runs after every transform it lists in `depends_on`, and
+// otherwise in declaration order.
+type transformOrder struct {
+	phases []string
+	// The maps below are keyed by transform block address, without the
+	// `[key]` suffix of for_each instances.
+	phase     map[string]string
+	dependsOn map[string][]string
+	position  map[string]int
+	ranges    map[string]hcl.Range
+}
+
+// newTransformOrder reads `phase` and `depends_on` from the transform blocks.
+// It fails when a phase isn't a literal string, when a transform depends on
+// one in a later phase, or when `depends_on` forms a cycle.
+func newTransformOrder(hclBlocks []*golden.HclBlock) (*transformOrder, error) {
+	o := &transformOrder{
+		phase:     make(map[string]string),
+		dependsOn: make(map[string][]string),
+		position:  make(map[string]int),
+		ranges:    make(map[string]hcl.Range),
+	}
+	var transforms []*golden.HclBlock
+	for _, b := range hclBlocks {
+		if b.Type == "transform" && len(b.Labels) == 2 {
+			transforms = append(transforms, b)
+		}
+	}
+	sort.SliceStable(transforms, func(i, j int) bool {
+		ri, rj := transforms[i].Range(), transforms[j].Range()
+		if ri.Filename != rj.Filename {
+			return ri.Filename < rj.Filename
+		}
+		return ri.Start.Byte < rj.Start.Byte
+	})
+	phaseIndex := make(map[string]int)
+	for i, b := range transforms {
+		address := transformBlockAddress(b)
+		phase, err := blockPhase(b)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := phaseIndex[phase]; !ok {
+			phaseIndex[phase] = len(o.phases)
+			o.phases = append(o.phases, phase)
+		}
+		o.phase[address] = phase
+		o.position[address] = i
+		o.ranges[address] = b.Range()
+		if attr, ok := b.Body.Attributes["depends_on"]; ok {
+			for _, traversal := range attr.Expr.Variables() {
+				if dep, ok := transformTraversalAddress(traversal); ok {
+					o.dependsOn[address] = append(o.dependsOn[address], dep)
+				}
+			}
+		}
+	}
+	for _, b := range transforms {
+		address := transformBlockAddress(b)
+		for _, dep := range o.dependsOn[address] {
+			if _, ok := o.phase[dep]; !ok {
+				continue
+			}
+			if phaseIndex[o.phase[dep]] > phaseIndex[o.phase[address]] {
+				return nil, fmt.Errorf("%s (%s) in phase %q depends on %s (%s) in the later phase %q", address, o.ranges[address].String(), o.phase[address], dep, o.ranges[dep].String(), o.phase[dep])
+			}
+		}
+	}
+	if err := o.checkCycles(transforms); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// TransformPhases returns the phases of the transform blocks in the order
+// they run. A config without transforms has the default phase only.
+func TransformPhases(hclBlocks []*golden.HclBlock) ([]string, error) {
+	o, err := newTransformOrder(hclBlocks)
+	if err != nil {
+		return nil, err
+	}
+	if len(o.phases) == 0 {
+		return []string{defaultPhase}, nil
+	}
+	return o.phases, nil
+}
+
+func blockPhase(b *golden.HclBlock) (string, error) {
+	attr, ok := b.Body.Attributes["phase"]
+	if !ok {
+		return defaultPhase, nil
+	}
+	value, diag := attr.Expr.Value(nil)
+	if diag.HasErrors() || value.IsNull() || !value.Type().Equals(cty.String) {
+		return "", fmt.Errorf("%s (%s): `phase` must be a literal string", transformBlockAddress(b), attr.Range().String())
+	}
+	return value.AsString(), nil
+}
+
+func (o *transformOrder) checkCycles(transforms []*golden.HclBlock) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var path []string
+	var visit func(address string) error
+	visit = func(address string) error {
+		switch state[address] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for path[start] != address {
+				start++
+			}
+			var cycle []string
+			for _, a := range append(path[start:], address) {
+				cycle = append(cycle, fmt.Sprintf("%s (%s)", a, o.ranges[a].String()))
+			}
+			return fmt.Errorf("dependency cycle between transforms: %s", strings.Join(cycle, " -> "))
+		}
+		state[address] = visiting
+		path = append(path, address)
+		for _, dep := range o.dependsOn[address] {
+			if _, ok := o.phase[dep]; !ok {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[address] = done
+		return nil
+	}
+	for _, b := range transforms {
+		if err := visit(transformBlockAddress(b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sort returns transforms in the order they must be applied. for_each
+// instances of one block keep the block's place, ordered by address.
+func (o *transformOrder) sort(transforms []Transform) []Transform {
+	if o == nil {
+		return transforms
+	}
+	phaseIndex := make(map[string]int, len(o.phases))
+	for i, p := range o.phases {
+		phaseIndex[p] = i
+	}
+	pending := append([]Transform{}, transforms...)
+	sort.SliceStable(pending, func(i, j int) bool {
+		ai, aj := transformBlockAddress(pending[i].HclBlock()), transformBlockAddress(pending[j].HclBlock())
+		if pi, pj := phaseIndex[o.phase[ai]], phaseIndex[o.phase[aj]]; pi != pj {
+			return pi < pj
+		}
+		if o.position[ai] != o.position[aj] {
+			return o.position[ai] < o.position[aj]
+		}
+		return pending[i].Address() < pending[j].Address()
+	})
+	remaining := make(map[string]int)
+	for _, t := range pending {
+		remaining[transformBlockAddress(t.HclBlock())]++
+	}
+	r := make([]Transform, 0, len(pending))
+	for len(pending) > 0 {
+		next := 0
+		for i, t := range pending {
+			if o.ready(transformBlockAddress(t.HclBlock()), remaining) {
+				next = i
+				break
+			}
+		}
+		t := pending[next]
+		pending = append(pending[:next], pending[next+1:]...)
+		remaining[transformBlockAddress(t.HclBlock())]--
+		r = append(r, t)
+	}
+	return r
+}
+
+func (o *transformOrder) phaseOf(t Transform) string {
+	if o == nil {
+		return defaultPhase
+	}
+	return o.phase[transformBlockAddress(t.HclBlock())]
+}
+
+// requires reports whether t depends on the transform at address, directly
+// or through other transforms.
+func (o *transformOrder) requires(t Transform, address string) bool {
+	if o == nil {
+		return false
+	}
+	target, _, _ := strings.Cut(address, "[")
+	visited := make(map[string]bool)
+	var visit func(address string) bool
+	visit = func(address string) bool {
+		if visited[address] {
+			return false
+		}
+		visited[address] = true
+		for _, dep := range o.dependsOn[address] {
+			if dep == target || visit(dep) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(transformBlockAddress(t.HclBlock()))
+}
+
+func (o *transformOrder) ready(address string, remaining map[string]int) bool {
+	for _, dep := range o.dependsOn[address] {
+		if remaining[dep] > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func transformBlockAddress(b *golden.HclBlock) string {
+	return strings.Join(append([]string{b.Type}, b.Labels...), ".")
+}
+
+// transformTraversalAddress returns the transform block address a reference
+// like `transform.update_in_place.tags` points to.
+func transformTraversalAddress(traversal hcl.Traversal) (string, bool) {
+	if traversal.RootName() != "transform" || len(traversal) < 3 {
+		return "", false
+	}
+	parts := []string{"transform"}
+	for _, t := range traversal[1:3] {
+		attr, ok := t.(hcl.TraverseAttr)
+		if !ok {
+			return "", false
+		}
+		parts = append(parts, attr.Name)
+	}
+	return strings.Join(parts, "."), true
+}
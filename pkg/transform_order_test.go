@@ -0,0 +1,163 @@
+package pkg_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/mapotf/pkg"
+	filesystem "github.com/Azure/mapotf/pkg/fs"
+	"github.com/prashantv/gostub"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const orderTestTerraformCode = `resource "fake_resource" "this" {
+}
+
+resource "fake_resource" "that" {
+}
+`
+
+func TestMetaProgrammingTFPlan_TransformsRunInPhaseAndDependencyOrder(t *testing.T) {
+	stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+		filepath.Join("terraform", "main.tf"): orderTestTerraformCode,
+		filepath.Join("mptf", "a.mptf.hcl"): `data "resource" fake_resource {
+  resource_type = "fake_resource"
+}
+
+transform "update_in_place" b {
+  target_block_address = "resource.fake_resource.this"
+  depends_on           = [transform.update_in_place.c]
+  asraw {
+    tags = "b"
+  }
+}
+
+transform "update_in_place" c {
+  target_block_address = "resource.fake_resource.this"
+  asraw {
+    tags = "c"
+  }
+}
+
+transform "update_in_place" cleanup {
+  phase                = "cleanup"
+  target_block_address = "resource.fake_resource.this"
+  asraw {
+    tags = "cleanup"
+  }
+}
+`,
+		filepath.Join("mptf", "b.mptf.hcl"): `transform "update_in_place" d {
+  for_each             = data.resource.fake_resource.result.fake_resource
+  target_block_address = each.value.mptf.block_address
+  asraw {
+    tags = "d"
+  }
+}
+`,
+	}))
+	defer stub.Reset()
+	_, plan := runPlan(t)
+	var addresses []string
+	for _, transform := range plan.Transforms {
+		addresses = append(addresses, transform.Address())
+	}
+	assert.Equal(t, []string{
+		"transform.update_in_place.c",
+		"transform.update_in_place.b",
+		"transform.update_in_place.d[that]",
+		"transform.update_in_place.d[this]",
+		"transform.update_in_place.cleanup",
+	}, addresses)
+
+	require.NoError(t, plan.Apply())
+	var applied []string
+	for _, r := range plan.Results() {
+		applied = append(applied, r.Address)
+	}
+	assert.Equal(t, addresses, applied)
+	content, err := afero.ReadFile(filesystem.Fs, filepath.Join("terraform", "main.tf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `tags = "cleanup"`)
+	assert.Contains(t, string(content), `tags = "d"`)
+}
+
+func TestNewMetaProgrammingTFConfig_InvalidTransformOrder(t *testing.T) {
+	cases := []struct {
+		desc          string
+		mptf          string
+		expectedError []string
+	}{
+		{
+			desc: "dependency cycle",
+			mptf: `transform "update_in_place" a {
+  target_block_address = "resource.fake_resource.this"
+  depends_on           = [transform.update_in_place.b]
+}
+
+transform "update_in_place" b {
+  target_block_address = "resource.fake_resource.this"
+  depends_on           = [transform.update_in_place.a]
+}
+`,
+			expectedError: []string{
+				"dependency cycle between transforms",
+				"transform.update_in_place.a (mptf/main.mptf.hcl:1,1-4,2) -> transform.update_in_place.b (mptf/main.mptf.hcl:6,1-9,2) -> transform.update_in_place.a",
+			},
+		},
+		{
+			desc: "depends on a later phase",
+			mptf: `transform "update_in_place" a {
+  target_block_address = "resource.fake_resource.this"
+  depends_on           = [transform.update_in_place.b]
+}
+
+transform "update_in_place" b {
+  phase                = "cleanup"
+  target_block_address = "resource.fake_resource.this"
+}
+`,
+			expectedError: []string{
+				`transform.update_in_place.a (mptf/main.mptf.hcl:1,1-4,2) in phase "default" depends on transform.update_in_place.b (mptf/main.mptf.hcl:6,1-9,2) in the later phase "cleanup"`,
+			},
+		},
+		{
+			desc: "non literal phase",
+			mptf: `variable "phase" {
+  type    = string
+  default = "cleanup"
+}
+
+transform "update_in_place" a {
+  phase                = var.phase
+  target_block_address = "resource.fake_resource.this"
+}
+`,
+			expectedError: []string{
+				"transform.update_in_place.a (mptf/main.mptf.hcl:7,3-35): `phase` must be a literal string",
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			stub := gostub.Stub(&filesystem.Fs, fakeFs(map[string]string{
+				filepath.Join("terraform", "main.tf"): orderTestTerraformCode,
+				filepath.Join("mptf", "main.mptf.hcl"): c.mptf,
+			}))
+			defer stub.Reset()
+			hclBlocks, err := pkg.LoadMPTFHclBlocks(false, "mptf")
+			require.NoError(t, err)
+			_, err = pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
+				Dir:    "terraform",
+				AbsDir: "terraform",
+			}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
+			require.Error(t, err)
+			for _, expected := range c.expectedError {
+				assert.Contains(t, err.Error(), expected)
+			}
+		})
+	}
+}
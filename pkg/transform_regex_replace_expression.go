@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/Azure/golden"
+	"github.com/Azure/mapotf/pkg/terraform"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
@@ -24,6 +25,11 @@ func (r *RegexReplaceExpressionTransform) Type() string {
 	return "regex_replace_expression"
 }
 
+// Idempotent is false: the replacement may match the regex again.
+func (r *RegexReplaceExpressionTransform) Idempotent() bool {
+	return false
+}
+
 func (r *RegexReplaceExpressionTransform) Apply() error {
 	cfg := r.Config().(*MetaProgrammingTFConfig)
 	re, err := regexp.Compile(r.Regex)
@@ -31,6 +37,10 @@ func (r *RegexReplaceExpressionTransform) Apply() error {
 		return err
 	}
 	for _, block := range cfg.allRootBlocks {
+		// `.tf.json` files are read-only, leave them alone rather than fail.
+		if terraform.IsJSONFile(block.Range().Filename) {
+			continue
+		}
 		if subErr := r.applyRegexReplace(block.WriteBlock.Body(), block.Range().Filename, re); subErr != nil {
 			err = multierror.Append(err, subErr)
 		}
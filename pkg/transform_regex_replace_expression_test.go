@@ -181,7 +181,7 @@ resource "fake_resource" {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, hclBlocks, nil, context.TODO())
+			}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 			require.NoError(t, err)
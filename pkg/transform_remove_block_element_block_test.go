@@ -188,7 +188,7 @@ resource "fake_resource" this {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+			}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 			require.NoError(t, err)
@@ -238,7 +238,7 @@ resource "fake_resource" this {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, hclBlocks, nil, context.TODO())
+	}, nil, hclBlocks, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 	require.NoError(t, err)
@@ -297,7 +297,7 @@ resource "fake_resource" this {
 	cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 		Dir:    "/",
 		AbsDir: "/",
-	}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+	}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 	require.NoError(t, err)
 	plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 	require.NoError(t, err)
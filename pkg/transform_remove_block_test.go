@@ -91,6 +91,52 @@ module "keep_module" {
   source = "./modules/keep"
   attr = "keep"
 }
+`,
+		},
+		{
+			desc: "remove_aliased_provider_block",
+			mptf: `
+transform "remove_block" this {
+  target_block_address = "provider.azurerm.secondary"
+}
+`,
+			tfConfig: `
+provider "azurerm" {
+  features {}
+}
+
+provider "azurerm" {
+  alias = "secondary"
+  features {}
+}
+`,
+			expected: `
+provider "azurerm" {
+  features {}
+}
+`,
+		},
+		{
+			desc: "remove_import_block",
+			mptf: `
+transform "remove_block" this {
+  target_block_address = "import.0"
+}
+`,
+			tfConfig: `
+import {
+  to = fake_resource.this
+  id = "this"
+}
+
+resource "fake_resource" "this" {
+  attr = "value"
+}
+`,
+			expected: `
+resource "fake_resource" "this" {
+  attr = "value"
+}
 `,
 		},
 		{
@@ -129,7 +175,7 @@ resource "fake_resource" "this" {
 			cfg, err := pkg.NewMetaProgrammingTFConfig(&pkg.TerraformModuleRef{
 				Dir:    "/",
 				AbsDir: "/",
-			}, nil, []*golden.HclBlock{hclBlock}, nil, context.TODO())
+			}, nil, []*golden.HclBlock{hclBlock}, nil, pkg.TerraformOptions{}, context.TODO())
 			require.NoError(t, err)
 			plan, err := pkg.RunMetaProgrammingTFPlan(cfg)
 			require.NoError(t, err)
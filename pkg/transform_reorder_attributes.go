@@ -364,7 +364,7 @@ func validateReorderComposition(transforms []Transform) error {
 			citations = append(citations, fmt.Sprintf("  - %s at %s (sort_body_alphabetically = %t)",
 				r.Address(), r.HclBlock().Range().String(), r.useSortBodyAlphabetically()))
 		}
-		return fmt.Errorf("reorder_attributes: %d transforms target %q with at least one using sort_body_alphabetically = false; this combination is not composable because non-alphabetical sort reads parse-side source positions that are not updated between Apply() calls; set `sort_body_alphabetically = true` on every colliding transform, merge them into a single transform, or filter the `for_each` set of one to exclude addresses handled by the other; colliding transforms:\n%s",
+		return fmt.Errorf("reorder_attributes: %d transforms target %q with at least one using sort_body_alphabetically = false; this combination is not composable because non-alphabetical sort reads parse-side source positions that are not updated between Apply() calls; set `sort_body_alphabetically = true` on every colliding transform, merge them into a single transform, put them in different phases so each one reads freshly loaded source positions, or filter the `for_each` set of one to exclude addresses handled by the other; colliding transforms:\n%s",
 			len(group), addr, strings.Join(citations, "\n"))
 	}
 	return nil